@@ -0,0 +1,219 @@
+package cache
+
+import (
+	"unsafe"
+
+	"github.com/segmentio/datastructures/v2/container/list"
+)
+
+// ARC is an Interface implementation of an adaptive replacement cache.
+//
+// Entries are first inserted in a recency segment (T1), and are moved to a
+// frequency segment (T2) when they are accessed again. Keys of evicted entries
+// are remembered in ghost lists (B1 and B2, for entries evicted from T1 and T2
+// respectively); inserting a key found in a ghost list signals that the cache
+// favored the wrong segment, and adjusts the target size p of T1 accordingly.
+// This lets the cache self-tune the balance between recency and frequency.
+//
+// Since the capacity of the cache is controlled by the program calling Evict,
+// the number of entries held in the cache at the time of an eviction is used
+// as the cache capacity to bound the target p and the size of the ghost lists.
+type ARC[K comparable, V any] struct {
+	index map[K]*list.Element[arcEntry[K, V]]
+	t1    list.List[arcEntry[K, V]]
+	t2    list.List[arcEntry[K, V]]
+	b1    list.List[arcEntry[K, V]]
+	b2    list.List[arcEntry[K, V]]
+	p     int
+}
+
+type arcSegment uint8
+
+const (
+	arcT1 arcSegment = iota
+	arcT2
+	arcB1
+	arcB2
+)
+
+type arcEntry[K comparable, V any] struct {
+	entry[K, V]
+	segment arcSegment
+}
+
+func (arc *ARC[K, V]) Len() int {
+	return arc.t1.Len() + arc.t2.Len()
+}
+
+// Target returns the current target size of the recency segment of the cache.
+func (arc *ARC[K, V]) Target() int {
+	return arc.p
+}
+
+func (arc *ARC[K, V]) Insert(key K, value V) (previous V, replaced bool) {
+	if arc.index == nil {
+		arc.index = make(map[K]*list.Element[arcEntry[K, V]])
+	}
+	e, ok := arc.index[key]
+	if !ok {
+		arc.index[key] = arc.t1.PushFront(arcEntry[K, V]{
+			entry: entry[K, V]{key: key, value: value},
+		})
+		return previous, false
+	}
+
+	switch e.Value.segment {
+	case arcT1, arcT2:
+		previous, replaced = e.Value.value, true
+	case arcB1:
+		arc.adapt(+arcDelta(arc.b2.Len(), arc.b1.Len()))
+	case arcB2:
+		arc.adapt(-arcDelta(arc.b1.Len(), arc.b2.Len()))
+	}
+	e.Value.value = value
+	arc.promote(e)
+	return previous, replaced
+}
+
+func (arc *ARC[K, V]) Lookup(key K) (value V, found bool) {
+	e, ok := arc.index[key]
+	if ok && arc.resident(e) {
+		arc.promote(e)
+		value, found = e.Value.value, true
+	}
+	return value, found
+}
+
+// Peek returns the value associated with key without promoting the entry.
+func (arc *ARC[K, V]) Peek(key K) (value V, found bool) {
+	e, ok := arc.index[key]
+	if ok && arc.resident(e) {
+		value, found = e.Value.value, true
+	}
+	return value, found
+}
+
+func (arc *ARC[K, V]) LookupOrInsert(key K, value V) (actual V, loaded bool) {
+	if e, ok := arc.index[key]; ok && arc.resident(e) {
+		arc.promote(e)
+		return e.Value.value, true
+	}
+	arc.Insert(key, value)
+	return value, false
+}
+
+func (arc *ARC[K, V]) Delete(key K) (value V, deleted bool) {
+	e, ok := arc.index[key]
+	if ok {
+		delete(arc.index, key)
+		arc.segmentOf(e).Remove(e)
+		if arc.resident(e) {
+			value, deleted = e.Value.value, true
+		}
+	}
+	return value, deleted
+}
+
+// Evict removes the least recently used entry of T1 if it holds more entries
+// than the target p, or of T2 otherwise. The key of the evicted entry is
+// retained in the corresponding ghost list.
+func (arc *ARC[K, V]) Evict() (key K, value V, evicted bool) {
+	c := arc.Len()
+	if c == 0 {
+		return key, value, false
+	}
+
+	var e *list.Element[arcEntry[K, V]]
+	if t1 := arc.t1.Len(); t1 != 0 && (t1 > arc.p || arc.t2.Len() == 0) {
+		e = arc.t1.Back()
+		arc.t1.Remove(e)
+		e.Value.segment = arcB1
+	} else {
+		e = arc.t2.Back()
+		arc.t2.Remove(e)
+		e.Value.segment = arcB2
+	}
+
+	key, value, evicted = e.Value.key, e.Value.value, true
+	e.Value.value = *new(V) // ghost entries only retain keys
+	arc.segmentOf(e).PushFrontElement(e)
+
+	for arc.t1.Len()+arc.b1.Len() > c && arc.b1.Len() != 0 {
+		arc.forget(&arc.b1)
+	}
+	for arc.Len()+arc.b1.Len()+arc.b2.Len() > 2*c && arc.b2.Len() != 0 {
+		arc.forget(&arc.b2)
+	}
+	return key, value, evicted
+}
+
+func (arc *ARC[K, V]) Range(f func(K, V) bool) {
+	for _, e := range arc.index {
+		if arc.resident(e) && !f(e.Value.key, e.Value.value) {
+			break
+		}
+	}
+}
+
+// MemoryUsage returns an estimate of the memory footprint of the cache, in
+// bytes. See LRU.MemoryUsage for details; entries of the ghost lists are
+// included in the estimate.
+func (arc *ARC[K, V]) MemoryUsage() int64 {
+	var key K
+	var elem list.Element[arcEntry[K, V]]
+	size := int64(unsafe.Sizeof(*arc))
+	size += int64(len(arc.index)) * int64(unsafe.Sizeof(elem)+unsafe.Sizeof(key)+unsafe.Sizeof(&elem))
+	return size
+}
+
+func (arc *ARC[K, V]) resident(e *list.Element[arcEntry[K, V]]) bool {
+	return e.Value.segment == arcT1 || e.Value.segment == arcT2
+}
+
+func (arc *ARC[K, V]) segmentOf(e *list.Element[arcEntry[K, V]]) *list.List[arcEntry[K, V]] {
+	switch e.Value.segment {
+	case arcT1:
+		return &arc.t1
+	case arcT2:
+		return &arc.t2
+	case arcB1:
+		return &arc.b1
+	default:
+		return &arc.b2
+	}
+}
+
+func (arc *ARC[K, V]) promote(e *list.Element[arcEntry[K, V]]) {
+	if e.Value.segment == arcT2 {
+		arc.t2.MoveToFront(e)
+		return
+	}
+	arc.segmentOf(e).Remove(e)
+	e.Value.segment = arcT2
+	arc.t2.PushFrontElement(e)
+}
+
+func (arc *ARC[K, V]) adapt(delta int) {
+	arc.p += delta
+	if c := arc.Len(); arc.p > c {
+		arc.p = c
+	}
+	if arc.p < 0 {
+		arc.p = 0
+	}
+}
+
+// arcDelta returns the amount by which the target p is adjusted on a hit in a
+// ghost list of size hit, the other ghost list being of size other.
+func arcDelta(other, hit int) int {
+	if d := other / hit; d > 1 {
+		return d
+	}
+	return 1
+}
+
+func (arc *ARC[K, V]) forget(ghosts *list.List[arcEntry[K, V]]) {
+	e := ghosts.Back()
+	ghosts.Remove(e)
+	delete(arc.index, e.Value.key)
+}
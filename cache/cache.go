@@ -8,6 +8,8 @@
 // goroutines
 package cache
 
+import "time"
+
 // Interface is the interface implemented by caches.
 type Interface[K comparable, V any] interface {
 	// Returns the number of items in the cache.
@@ -20,6 +22,11 @@ type Interface[K comparable, V any] interface {
 	// Returns the value associated with the given key in the cache.
 	Lookup(key K) (value V, found bool)
 
+	// Returns the value associated with the given key in the cache if it
+	// exists, otherwise inserts the value passed as argument and returns it.
+	// The loaded result is true if the value was found in the cache.
+	LookupOrInsert(key K, value V) (actual V, loaded bool)
+
 	// Deletes an item from the cache.
 	Delete(key K) (value V, deleted bool)
 
@@ -31,6 +38,12 @@ type Interface[K comparable, V any] interface {
 	Range(f func(K, V) bool)
 }
 
+// Entry is a key/value pair held in a cache.
+type Entry[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
 // Stats contains counters tracking usage of a cache.
 type Stats struct {
 	Inserts   int64
@@ -52,6 +65,7 @@ type Cache[K comparable, V any] struct {
 	hits      int64
 	evictions int64
 	backend   Interface[K, V]
+	latency   func(op string, d time.Duration)
 }
 
 func (c *Cache[K, V]) Init(backend Interface[K, V]) {
@@ -64,6 +78,38 @@ func (c *Cache[K, V]) Init(backend Interface[K, V]) {
 	c.backend = backend
 }
 
+// SwapBackend replaces the caching implementation of c with backend, which is
+// populated with the entries of the current backend first. This allows changing
+// the caching strategy of a live cache without losing its entries. The usage
+// statistics of the cache are retained.
+//
+// The entries are inserted in the order they are presented by Range, which is
+// unspecified, so the eviction order of the previous backend is not preserved.
+func (c *Cache[K, V]) SwapBackend(backend Interface[K, V]) {
+	if c.backend != nil {
+		c.backend.Range(func(key K, value V) bool {
+			backend.Insert(key, value)
+			return true
+		})
+	}
+	c.backend = backend
+}
+
+// SetLatencyHook installs a function called after each Insert, Lookup,
+// LookupOrInsert, Delete, and Evict operation with the name of the operation
+// ("insert", "lookup", "lookupOrInsert", "delete", or "evict") and the time it
+// took to complete. Setting the hook to
+// nil disables it, in which case no time measurements are made.
+//
+// The hook is retained when the cache is re-initialized by a call to Init.
+func (c *Cache[K, V]) SetLatencyHook(hook func(op string, d time.Duration)) {
+	c.latency = hook
+}
+
+func (c *Cache[K, V]) observe(op string, start time.Time) {
+	c.latency(op, time.Since(start))
+}
+
 func (c *Cache[K, V]) Len() int {
 	if c.backend != nil {
 		return c.backend.Len()
@@ -72,6 +118,9 @@ func (c *Cache[K, V]) Len() int {
 }
 
 func (c *Cache[K, V]) Insert(key K, value V) (previous V, replaced bool) {
+	if c.latency != nil {
+		defer c.observe("insert", time.Now())
+	}
 	if c.backend == nil {
 		c.backend = new(LRU[K, V])
 	}
@@ -85,6 +134,9 @@ func (c *Cache[K, V]) Insert(key K, value V) (previous V, replaced bool) {
 }
 
 func (c *Cache[K, V]) Lookup(key K) (value V, found bool) {
+	if c.latency != nil {
+		defer c.observe("lookup", time.Now())
+	}
 	if c.backend != nil {
 		value, found = c.backend.Lookup(key)
 		c.lookups++
@@ -95,7 +147,36 @@ func (c *Cache[K, V]) Lookup(key K) (value V, found bool) {
 	return value, found
 }
 
+// Peek returns the value associated with key without affecting the eviction
+// order of entries. Peeks are not counted as lookups in the cache statistics.
+func (c *Cache[K, V]) Peek(key K) (value V, found bool) {
+	if c.backend != nil {
+		value, found = peek(c.backend, key)
+	}
+	return value, found
+}
+
+func (c *Cache[K, V]) LookupOrInsert(key K, value V) (actual V, loaded bool) {
+	if c.latency != nil {
+		defer c.observe("lookupOrInsert", time.Now())
+	}
+	if c.backend == nil {
+		c.backend = new(LRU[K, V])
+	}
+	actual, loaded = c.backend.LookupOrInsert(key, value)
+	c.lookups++
+	if loaded {
+		c.hits++
+	} else {
+		c.inserts++
+	}
+	return actual, loaded
+}
+
 func (c *Cache[K, V]) Delete(key K) (value V, deleted bool) {
+	if c.latency != nil {
+		defer c.observe("delete", time.Now())
+	}
 	if c.backend != nil {
 		value, deleted = c.backend.Delete(key)
 		if deleted {
@@ -106,6 +187,9 @@ func (c *Cache[K, V]) Delete(key K) (value V, deleted bool) {
 }
 
 func (c *Cache[K, V]) Evict() (key K, value V, evicted bool) {
+	if c.latency != nil {
+		defer c.observe("evict", time.Now())
+	}
 	if c.backend != nil {
 		key, value, evicted = c.backend.Evict()
 		if evicted {
@@ -1,15 +1,799 @@
 package cache
 
-import "testing"
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/segmentio/datastructures/v2/compare"
+)
 
 func TestCache(t *testing.T) {
 	testCache(t, func() Interface[int, int] { return new(Cache[int, int]) })
 }
 
+func TestCacheLatencyHook(t *testing.T) {
+	ops := []string{}
+	c := new(Cache[int, int])
+	c.SetLatencyHook(func(op string, d time.Duration) {
+		if d < 0 {
+			t.Errorf("negative latency reported for %s: %v", op, d)
+		}
+		ops = append(ops, op)
+	})
+
+	c.Insert(1, 10)
+	c.Lookup(1)
+	c.LookupOrInsert(1, 12)
+	c.Delete(1)
+	c.Evict()
+
+	c.SetLatencyHook(nil)
+	c.Insert(2, 11)
+	c.LookupOrInsert(3, 13)
+
+	want := []string{"insert", "lookup", "lookupOrInsert", "delete", "evict"}
+	if len(ops) != len(want) {
+		t.Fatalf("wrong operations reported: got=%v want=%v", ops, want)
+	}
+	for i := range ops {
+		if ops[i] != want[i] {
+			t.Errorf("wrong operation reported at index %d: got=%s want=%s", i, ops[i], want[i])
+		}
+	}
+}
+
+func TestCacheSwapBackend(t *testing.T) {
+	c := new(Cache[int, int])
+	c.Insert(1, 10)
+	c.Insert(2, 11)
+	c.Lookup(1)
+
+	slru := new(SLRU[int, int])
+	c.SwapBackend(slru)
+
+	if n := slru.Len(); n != 2 {
+		t.Errorf("wrong number of entries in the new backend: got=%d want=2", n)
+	}
+	assertCacheLookup(t, c, 1, 10, true)
+	assertCacheLookup(t, c, 2, 11, true)
+
+	if stats := c.Stats(); stats.Inserts != 2 || stats.Lookups != 3 || stats.Hits != 3 {
+		t.Errorf("wrong stats after swapping backend: %+v", stats)
+	}
+
+	empty := new(Cache[int, int])
+	empty.SwapBackend(new(ARC[int, int]))
+	empty.Insert(1, 10)
+	assertCacheLookup(t, empty, 1, 10, true)
+}
+
 func TestLRU(t *testing.T) {
 	testCache(t, func() Interface[int, int] { return new(LRU[int, int]) })
 }
 
+func TestMRU(t *testing.T) {
+	testCache(t, func() Interface[int, int] { return new(MRU[int, int]) })
+}
+
+func TestMRUEvict(t *testing.T) {
+	mru := new(MRU[int, int])
+	mru.Insert(1, 10)
+	mru.Insert(2, 11)
+	mru.Insert(3, 12)
+	mru.Lookup(1)
+
+	for _, want := range []int{1, 3, 2} {
+		if k, _, evicted := mru.Evict(); !evicted {
+			t.Fatal("non-empty cache failed to evict anything")
+		} else if k != want {
+			t.Errorf("wrong key evicted: got=%d want=%d", k, want)
+		}
+	}
+
+	if _, _, evicted := mru.Evict(); evicted {
+		t.Error("empty cache evicted an entry")
+	}
+}
+
+func TestSLRU(t *testing.T) {
+	testCache(t, func() Interface[int, int] { return new(SLRU[int, int]) })
+}
+
+func TestSLRUScanResistance(t *testing.T) {
+	slru := new(SLRU[int, int])
+
+	// Keys 0 to 9 are hot, they are accessed multiple times.
+	for i := 0; i < 10; i++ {
+		slru.Insert(i, i)
+		slru.Lookup(i)
+	}
+
+	// Keys 100 to 199 are accessed once, simulating a scan.
+	for i := 100; i < 200; i++ {
+		slru.Insert(i, i)
+	}
+
+	for i := 0; i < 100; i++ {
+		if k, _, evicted := slru.Evict(); !evicted {
+			t.Fatal("non-empty cache failed to evict anything")
+		} else if k < 100 {
+			t.Fatalf("hot key=%d evicted before scanned keys", k)
+		}
+	}
+
+	if n := slru.Len(); n != 10 {
+		t.Errorf("wrong number of cache entries: got=%d want=10", n)
+	}
+}
+
+func TestARC(t *testing.T) {
+	testCache(t, func() Interface[int, int] { return new(ARC[int, int]) })
+}
+
+func TestARCAdapt(t *testing.T) {
+	arc := new(ARC[int, int])
+
+	// Keys 0 to 9 are hot, they are accessed multiple times.
+	for i := 0; i < 10; i++ {
+		arc.Insert(i, i)
+		arc.Lookup(i)
+	}
+
+	// Keys 100 to 109 are accessed once, then evicted.
+	for i := 100; i < 110; i++ {
+		arc.Insert(i, i)
+	}
+	for i := 0; i < 10; i++ {
+		if k, _, evicted := arc.Evict(); !evicted {
+			t.Fatal("non-empty cache failed to evict anything")
+		} else if k < 100 {
+			t.Fatalf("hot key=%d evicted before recent keys", k)
+		}
+	}
+	if p := arc.Target(); p != 0 {
+		t.Errorf("wrong target: got=%d want=0", p)
+	}
+
+	// Inserting evicted keys again hits the recency ghost list, which grows
+	// the target size of the recency segment.
+	arc.Insert(100, 100)
+	if p := arc.Target(); p != 1 {
+		t.Errorf("wrong target after ghost hit: got=%d want=1", p)
+	}
+	if _, found := arc.Lookup(101); found {
+		t.Error("ghost entry reported as found")
+	}
+	if v, found := arc.Lookup(100); !found || v != 100 {
+		t.Errorf("wrong value for re-inserted key: got=%d,%t want=100,true", v, found)
+	}
+	if n := arc.Len(); n != 11 {
+		t.Errorf("wrong number of cache entries: got=%d want=11", n)
+	}
+	if _, deleted := arc.Delete(101); deleted {
+		t.Error("deleting a ghost entry reported a deleted value")
+	}
+}
+
+func TestSoftLRU(t *testing.T) {
+	testCache(t, func() Interface[int, int] {
+		c := new(SoftLRU[int, int])
+		c.SetSoftCapacity(2)
+		return c
+	})
+}
+
+func TestSoftLRUEvict(t *testing.T) {
+	c := new(SoftLRU[int, int])
+	c.SetSoftCapacity(2)
+
+	for i := 0; i < 5; i++ {
+		c.Insert(i, 10+i)
+	}
+	for i := 0; i < 3; i++ {
+		if k, _, evicted := c.Evict(); !evicted {
+			t.Fatal("non-empty cache failed to evict anything")
+		} else if k != i {
+			t.Errorf("wrong key evicted: got=%d want=%d", k, i)
+		}
+	}
+
+	if n := c.Len(); n != 2 {
+		t.Errorf("wrong number of cache entries: got=%d want=2", n)
+	}
+	if n := c.SoftLen(); n != 2 {
+		t.Errorf("wrong number of soft entries: got=%d want=2", n)
+	}
+
+	// Key 0 was dropped from the soft tier, keys 1 and 2 are still there.
+	assertCacheLookup(t, c, 0, 0, false)
+	assertCacheLookup(t, c, 2, 12, true)
+
+	if n := c.Len(); n != 3 {
+		t.Errorf("wrong number of cache entries after rescue: got=%d want=3", n)
+	}
+	if n := c.SoftLen(); n != 1 {
+		t.Errorf("wrong number of soft entries after rescue: got=%d want=1", n)
+	}
+
+	if v, deleted := c.Delete(1); !deleted || v != 11 {
+		t.Errorf("wrong result deleting soft entry: got=%d,%t want=11,true", v, deleted)
+	}
+
+	c.SetSoftCapacity(0)
+	c.Evict()
+	if n := c.SoftLen(); n != 0 {
+		t.Errorf("wrong number of soft entries without soft capacity: got=%d want=0", n)
+	}
+}
+
+func TestRaceChecked(t *testing.T) {
+	testCache(t, func() Interface[int, int] { return new(RaceChecked[int, int]) })
+}
+
+func TestRaceCheckedConcurrentAccess(t *testing.T) {
+	c := new(RaceChecked[int, int])
+	c.Insert(1, 10)
+
+	var recovered any
+	c.Range(func(int, int) bool {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			defer func() { recovered = recover() }()
+			c.Lookup(1)
+		}()
+		<-done
+		return true
+	})
+
+	if recovered == nil {
+		t.Error("concurrent access to the cache was not detected")
+	}
+
+	// The cache must be usable again once the overlapping calls returned.
+	assertCacheLookup(t, c, 1, 10, true)
+}
+
+func TestInstrumented(t *testing.T) {
+	testCache(t, func() Interface[int, int] { return new(Instrumented[int, int]) })
+}
+
+func TestInstrumentedTopKeys(t *testing.T) {
+	c := new(Instrumented[int, int])
+	for i := 0; i < 5; i++ {
+		c.Insert(i, 10*i)
+	}
+	for i := 0; i < 5; i++ {
+		for j := 0; j < i; j++ {
+			c.Lookup(i)
+		}
+	}
+	c.Lookup(42) // misses are not counted
+
+	if top := fmt.Sprint(c.TopKeys(3)); top != "[{4 4} {3 3} {2 2}]" {
+		t.Errorf("wrong top keys: got=%s want=[{4 4} {3 3} {2 2}]", top)
+	}
+	if top := c.TopKeys(10); len(top) != 4 {
+		t.Errorf("wrong number of top keys: got=%d want=4", len(top))
+	}
+
+	c.Delete(4)
+	c.Insert(3, 0) // replacing a value retains the counter
+	if top := fmt.Sprint(c.TopKeys(2)); top != "[{3 3} {2 2}]" {
+		t.Errorf("wrong top keys after delete: got=%s want=[{3 3} {2 2}]", top)
+	}
+
+	c.Insert(4, 40)
+	if hits := c.Hits(4); hits != 0 {
+		t.Errorf("wrong hits of reinserted key: got=%d want=0", hits)
+	}
+}
+
+func TestInstrumentedImplicitEviction(t *testing.T) {
+	c := new(Instrumented[int, int])
+	c.Init(NewRingCache[int, int](2, hashInt))
+	c.Insert(1, 10)
+	c.Insert(2, 20)
+	c.Lookup(2)
+	c.Lookup(2)
+	c.Insert(3, 30) // evicts key 1 or 2 without calling Evict
+	c.Lookup(3)
+
+	for _, k := range c.TopKeys(10) {
+		if _, found := c.backend.Lookup(k.Key); !found {
+			t.Errorf("top keys contain a key which is not in the cache: %d", k.Key)
+		}
+	}
+}
+
+func TestInstrumentedTopKeysWithoutPeek(t *testing.T) {
+	backend := &rangeCounter{Interface: NewRingCache[int, int](2, hashInt)}
+	c := new(Instrumented[int, int])
+	c.Init(backend)
+	for i := 0; i < 3; i++ {
+		c.Insert(i, 10*i)
+		c.Lookup(i)
+		c.Lookup(i)
+	}
+
+	top := c.TopKeys(10)
+	if backend.ranges != 1 {
+		t.Errorf("wrong number of scans of the backend: got=%d want=1", backend.ranges)
+	}
+	if len(top) != backend.Len() {
+		t.Errorf("wrong number of top keys: got=%d want=%d", len(top), backend.Len())
+	}
+	for _, k := range top {
+		if _, found := backend.Lookup(k.Key); !found {
+			t.Errorf("top keys contain a key which is not in the cache: %d", k.Key)
+		}
+		if k.Hits != 2 {
+			t.Errorf("wrong hits of key=%d: got=%d want=2", k.Key, k.Hits)
+		}
+	}
+	if n := len(c.hits); n != len(top) {
+		t.Errorf("counters of evicted keys were retained: got=%d want=%d", n, len(top))
+	}
+}
+
+// rangeCounter wraps a cache backend, hiding its Peek method and counting the
+// calls to Range.
+type rangeCounter struct {
+	Interface[int, int]
+	ranges int
+}
+
+func (r *rangeCounter) Range(f func(int, int) bool) {
+	r.ranges++
+	r.Interface.Range(f)
+}
+
+func TestClock(t *testing.T) {
+	testCache(t, func() Interface[int, int] { return new(Clock[int, int]) })
+}
+
+func TestClockEvict(t *testing.T) {
+	c := new(Clock[int, int])
+	for i := 0; i < 5; i++ {
+		c.Insert(i, i)
+	}
+	c.Lookup(0)
+	c.Lookup(2)
+
+	// Referenced keys get a second chance, the hand clears their bit and
+	// moves on to the next entry.
+	for _, want := range []int{1, 3, 4, 0, 2} {
+		if k, _, evicted := c.Evict(); !evicted {
+			t.Fatal("non-empty cache failed to evict anything")
+		} else if k != want {
+			t.Errorf("wrong key evicted: got=%d want=%d", k, want)
+		}
+	}
+
+	// Slots freed by evictions are reused by new entries.
+	c.Insert(5, 5)
+	if n := len(c.slots); n != 5 {
+		t.Errorf("wrong number of slots: got=%d want=5", n)
+	}
+	assertCacheLookup(t, c, 5, 5, true)
+}
+
+func hashInt(k int) uint64 { return uint64(k) * 0x9E3779B97F4A7C15 }
+
+func TestRingCache(t *testing.T) {
+	testCache(t, func() Interface[int, int] { return NewRingCache[int, int](16, hashInt) })
+}
+
+func TestRingCacheModel(t *testing.T) {
+	const capacity = 50
+	c := NewRingCache[int, int](capacity, hashInt)
+	model := make(map[int]int)
+	prng := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 10000; i++ {
+		k := prng.Intn(200)
+		switch prng.Intn(4) {
+		case 0:
+			v, deleted := c.Delete(k)
+			if w, ok := model[k]; ok != deleted || v != w {
+				t.Fatalf("wrong result deleting key=%d: got=%d,%t want=%d,%t", k, v, deleted, w, ok)
+			}
+			delete(model, k)
+		case 1:
+			if len(model) == capacity {
+				ek, ev, _ := c.Evict()
+				if w, ok := model[ek]; !ok || w != ev {
+					t.Fatalf("wrong entry evicted: %d:%d", ek, ev)
+				}
+				delete(model, ek)
+			}
+			c.Insert(k, i)
+			model[k] = i
+		default:
+			v, found := c.Lookup(k)
+			if w, ok := model[k]; ok != found || v != w {
+				t.Fatalf("wrong result looking up key=%d: got=%d,%t want=%d,%t", k, v, found, w, ok)
+			}
+		}
+		if n := c.Len(); n != len(model) {
+			t.Fatalf("wrong number of cache entries: got=%d want=%d", n, len(model))
+		}
+	}
+}
+
+func TestRingCacheInsertFull(t *testing.T) {
+	c := NewRingCache[int, int](2, hashInt)
+	c.Insert(1, 10)
+	c.Insert(2, 11)
+	c.Lookup(1)
+	c.Insert(3, 12)
+
+	if n := c.Len(); n != 2 {
+		t.Errorf("wrong number of cache entries: got=%d want=2", n)
+	}
+	// Key 1 was referenced, so the clock hand selected key 2.
+	assertCacheLookup(t, c, 1, 10, true)
+	assertCacheLookup(t, c, 2, 0, false)
+	assertCacheLookup(t, c, 3, 12, true)
+}
+
+func TestRingCacheAllocs(t *testing.T) {
+	c := NewRingCache[int, int](100, hashInt)
+	i := 0
+	allocs := testing.AllocsPerRun(1000, func() {
+		c.Insert(i, i)
+		c.Lookup(i / 2)
+		if i%3 == 0 {
+			c.Delete(i - 1)
+		}
+		i++
+	})
+	if allocs != 0 {
+		t.Errorf("wrong number of allocations per operation: got=%g want=0", allocs)
+	}
+}
+
+func TestPriorityCache(t *testing.T) {
+	c := NewPriorityCache[string, int, int](compare.Function[int])
+	c.Insert("a", 3, 1)
+	c.Insert("b", 1, 2)
+	c.Insert("c", 2, 3)
+	c.Insert("d", 1, 4)
+
+	if previous, replaced := c.Insert("a", 0, 5); !replaced {
+		t.Error("inserting existing key did not replace the previous entry")
+	} else if previous != 1 {
+		t.Errorf("wrong replaced value returned: got=%v want=1", previous)
+	}
+
+	if !c.UpdatePriority("b", 4) {
+		t.Error("updating the priority of an existing key failed")
+	}
+	if c.UpdatePriority("z", 4) {
+		t.Error("updating the priority of a non-existing key succeeded")
+	}
+	if p, _ := c.Priority("b"); p != 4 {
+		t.Errorf("wrong priority for key=b: got=%d want=4", p)
+	}
+
+	for _, want := range []string{"a", "d", "c", "b"} {
+		k, _, evicted := c.Evict()
+		if !evicted {
+			t.Fatal("non-empty cache failed to evict anything")
+		}
+		if k != want {
+			t.Errorf("wrong key evicted: got=%s want=%s", k, want)
+		}
+	}
+
+	if _, _, evicted := c.Evict(); evicted {
+		t.Error("empty cache evicted an entry")
+	}
+	if n := c.Len(); n != 0 {
+		t.Errorf("wrong number of cache entries: got=%d want=0", n)
+	}
+}
+
+func TestSequencedLRU(t *testing.T) {
+	c := NewSequencedLRU[string, int, int](compare.Function[int])
+	c.Insert("a", 1, 10)
+	c.Insert("b", 2, 11)
+	c.Insert("c", 2, 12)
+	c.Insert("d", 3, 13)
+	c.Insert("e", 4, 14)
+
+	// Replacing the value of "a" moves it to a higher sequence.
+	if v, replaced := c.Insert("a", 5, 15); !replaced || v != 10 {
+		t.Errorf("wrong result replacing entry: got=%d,%t want=10,true", v, replaced)
+	}
+	if seq, _ := c.Sequence("a"); seq != 5 {
+		t.Errorf("wrong sequence: got=%d want=5", seq)
+	}
+
+	if n := c.TrimBelowSeq(3); n != 2 {
+		t.Errorf("wrong number of entries trimmed: got=%d want=2", n)
+	}
+	if n := c.Len(); n != 3 {
+		t.Errorf("wrong number of cache entries: got=%d want=3", n)
+	}
+	for _, key := range []string{"b", "c"} {
+		if _, found := c.Lookup(key); found {
+			t.Errorf("trimmed key=%s found in the cache", key)
+		}
+	}
+
+	// Trimming does not affect the recency order of the remaining entries.
+	c.Lookup("d")
+	for _, want := range []string{"e", "a", "d"} {
+		if k, _, evicted := c.Evict(); !evicted {
+			t.Fatal("non-empty cache failed to evict anything")
+		} else if k != want {
+			t.Errorf("wrong key evicted: got=%s want=%s", k, want)
+		}
+	}
+
+	if n := c.TrimBelowSeq(100); n != 0 {
+		t.Errorf("wrong number of entries trimmed from empty cache: got=%d want=0", n)
+	}
+}
+
+func TestRefCounted(t *testing.T) {
+	testCache(t, func() Interface[int, int] { return new(RefCounted[int, int]) })
+}
+
+func TestRefCountedEvict(t *testing.T) {
+	rc := new(RefCounted[int, int])
+	rc.Insert(1, 10)
+	rc.Insert(2, 11)
+
+	rc.Lookup(1)
+	rc.LookupOrInsert(1, 0)
+	rc.Lookup(2)
+
+	if n := rc.Refs(1); n != 2 {
+		t.Errorf("wrong number of references for key=1: got=%d want=2", n)
+	}
+	if k, _, evicted := rc.Evict(); evicted {
+		t.Errorf("evicted referenced key=%d", k)
+	}
+
+	if n := rc.Release(2); n != 0 {
+		t.Errorf("wrong number of references after release: got=%d want=0", n)
+	}
+	if k, _, evicted := rc.Evict(); !evicted || k != 2 {
+		t.Errorf("wrong eviction result: key=%d evicted=%t", k, evicted)
+	}
+
+	rc.Release(1)
+	if k, _, evicted := rc.Evict(); evicted {
+		t.Errorf("evicted referenced key=%d", k)
+	}
+	rc.Release(1)
+	if k, _, evicted := rc.Evict(); !evicted || k != 1 {
+		t.Errorf("wrong eviction result: key=%d evicted=%t", k, evicted)
+	}
+
+	if n := rc.Release(1); n != 0 {
+		t.Errorf("releasing an unreferenced key returned non-zero references: %d", n)
+	}
+}
+
+func TestLRUProtect(t *testing.T) {
+	lru := new(LRU[int, int])
+	lru.Insert(1, 10)
+	lru.Insert(2, 11)
+	lru.Insert(3, 12)
+
+	lru.Protect(1)
+	lru.Protect(3)
+
+	if k, _, evicted := lru.Evict(); !evicted {
+		t.Error("cache with unprotected entries failed to evict anything")
+	} else if k != 2 {
+		t.Errorf("wrong key evicted: got=%d want=2", k)
+	}
+
+	if k, _, evicted := lru.Evict(); evicted {
+		t.Errorf("evicted protected key=%d", k)
+	}
+
+	lru.Unprotect(3)
+
+	if k, _, evicted := lru.Evict(); !evicted {
+		t.Error("cache failed to evict unprotected key")
+	} else if k != 3 {
+		t.Errorf("wrong key evicted: got=%d want=3", k)
+	}
+
+	if n := lru.Len(); n != 1 {
+		t.Errorf("wrong number of cache entries: got=%d want=1", n)
+	}
+}
+
+func TestLRUBulkInsert(t *testing.T) {
+	lru := new(LRU[int, int])
+	lru.Insert(1, 10)
+
+	lru.BulkInsert([]Entry[int, int]{
+		{Key: 2, Value: 11},
+		{Key: 1, Value: 12},
+		{Key: 3, Value: 13},
+		{Key: 2, Value: 14},
+	})
+
+	if n := lru.Len(); n != 3 {
+		t.Errorf("wrong number of cache entries: got=%d want=3", n)
+	}
+	assertCacheLookup(t, lru, 1, 12, true)
+	assertCacheLookup(t, lru, 2, 14, true)
+	assertCacheLookup(t, lru, 3, 13, true)
+
+	// Lookups moved the keys to the front in order 1, 2, 3.
+	for _, want := range []int{1, 2, 3} {
+		if k, _, evicted := lru.Evict(); !evicted {
+			t.Fatal("non-empty cache failed to evict anything")
+		} else if k != want {
+			t.Errorf("wrong key evicted: got=%d want=%d", k, want)
+		}
+	}
+}
+
+func TestLRUPromotionPolicy(t *testing.T) {
+	lru := new(LRU[int, int])
+	lru.SetPromotionPolicy(func(hits int) bool { return hits >= 2 })
+	lru.Insert(1, 10)
+	lru.Insert(2, 11)
+	lru.Insert(3, 12)
+
+	// The first lookup does not promote key=1, the second does.
+	lru.Lookup(1)
+	if k, _, _ := lru.Evict(); k != 1 {
+		t.Errorf("wrong key evicted after first lookup: got=%d want=1", k)
+	}
+
+	lru.Insert(1, 10)
+	lru.Lookup(2)
+	lru.Lookup(2)
+	if k, _, _ := lru.Evict(); k != 3 {
+		t.Errorf("wrong key evicted after second lookup: got=%d want=3", k)
+	}
+
+	// Hits are counted from the last promotion.
+	lru.Lookup(1)
+	lru.Lookup(1)
+	lru.Lookup(2)
+	if k, _, _ := lru.Evict(); k != 2 {
+		t.Errorf("wrong key evicted after promotion: got=%d want=2", k)
+	}
+
+	lru.SetPromotionPolicy(nil)
+	lru.Insert(4, 13)
+	lru.Lookup(1)
+	if k, _, _ := lru.Evict(); k != 4 {
+		t.Errorf("wrong key evicted with default policy: got=%d want=4", k)
+	}
+}
+
+func TestLRURangeEvictionOrder(t *testing.T) {
+	lru := new(LRU[int, int])
+	for i := 0; i < 6; i++ {
+		lru.Insert(i, 10*i)
+	}
+	lru.Lookup(0)
+
+	keys := []int{}
+	lru.RangeEvictionOrder(func(k, v int) bool {
+		keys = append(keys, k)
+		return true
+	})
+	if fmt.Sprint(keys) != "[1 2 3 4 5 0]" {
+		t.Errorf("wrong eviction order: got=%v want=[1 2 3 4 5 0]", keys)
+	}
+
+	// Delete the odd keys among the first four candidates.
+	n := 0
+	lru.RangeEvictionOrder(func(k, v int) bool {
+		if k%2 != 0 {
+			lru.Delete(k)
+		}
+		n++
+		return n < 4
+	})
+	if lru.Len() != 4 {
+		t.Errorf("wrong number of cache entries: got=%d want=4", lru.Len())
+	}
+	for _, want := range []int{2, 4, 5, 0} {
+		if k, _, _ := lru.Evict(); k != want {
+			t.Errorf("wrong key evicted: got=%d want=%d", k, want)
+		}
+	}
+}
+
+func TestLRUMemoryUsage(t *testing.T) {
+	lru := new(LRU[int, int])
+	empty := lru.MemoryUsage()
+
+	lru.Insert(1, 10)
+	entry := lru.MemoryUsage() - empty
+	if entry <= 0 {
+		t.Fatalf("memory usage did not grow after inserting an entry: %d", entry)
+	}
+
+	lru.Insert(2, 11)
+	if size := lru.MemoryUsage() - empty; size != 2*entry {
+		t.Errorf("wrong memory usage of cache entries: got=%d want=%d", size, 2*entry)
+	}
+
+	lru.Delete(1)
+	lru.Delete(2)
+
+	if size := lru.MemoryUsage(); size != empty {
+		t.Errorf("wrong memory usage of empty cache: got=%d want=%d", size, empty)
+	}
+}
+
+func TestFreeze(t *testing.T) {
+	lru := new(LRU[int, int])
+	lru.Insert(1, 10)
+	lru.Insert(2, 20)
+	lru.Insert(3, 30)
+
+	view := Freeze[int, int](lru)
+	if n := view.Len(); n != 3 {
+		t.Errorf("wrong number of cache entries: got=%d want=3", n)
+	}
+
+	// Peeking at the least recently used entry must not protect it from
+	// being evicted.
+	if v, ok := view.Peek(1); !ok || v != 10 {
+		t.Errorf("wrong value peeked from cache: got=%d,%t want=10,true", v, ok)
+	}
+	if _, ok := view.Peek(4); ok {
+		t.Error("peeking at a key not in the cache reported it as found")
+	}
+	if k, _, _ := lru.Evict(); k != 1 {
+		t.Errorf("wrong key evicted after peek: got=%d want=1", k)
+	}
+
+	// Changes to the cache are visible through the view.
+	lru.Insert(4, 40)
+	if v, ok := view.Peek(4); !ok || v != 40 {
+		t.Errorf("wrong value peeked from cache: got=%d,%t want=40,true", v, ok)
+	}
+
+	sum := 0
+	view.Range(func(k, v int) bool { sum += v; return true })
+	if sum != 90 {
+		t.Errorf("wrong sum of values in range: got=%d want=90", sum)
+	}
+}
+
+func TestFreezeWithoutPeek(t *testing.T) {
+	// RaceChecked has no Peek method, the view falls back to scanning the
+	// entries of the cache.
+	c := new(RaceChecked[int, int])
+	c.Insert(1, 10)
+	c.Insert(2, 20)
+
+	view := Freeze[int, int](c)
+	if v, ok := view.Peek(2); !ok || v != 20 {
+		t.Errorf("wrong value peeked from cache: got=%d,%t want=20,true", v, ok)
+	}
+	if _, ok := view.Peek(3); ok {
+		t.Error("peeking at a key not in the cache reported it as found")
+	}
+
+	var empty ReadOnly[int, int]
+	if n := empty.Len(); n != 0 {
+		t.Errorf("wrong number of cache entries: got=%d want=0", n)
+	}
+	if _, ok := empty.Peek(1); ok {
+		t.Error("peeking at the zero-value reported a key as found")
+	}
+}
+
 func testCache(t *testing.T, newCache func() Interface[int, int]) {
 	tests := []struct {
 		scenario string
@@ -44,6 +828,11 @@ func testCache(t *testing.T, newCache func() Interface[int, int]) {
 			scenario: "inserting entries for existing keys replaces the previous values",
 			function: testCacheInsertAndReplace,
 		},
+
+		{
+			scenario: "looking up or inserting entries returns existing values or inserts new ones",
+			function: testCacheLookupOrInsert,
+		},
 	}
 
 	for _, test := range tests {
@@ -134,6 +923,25 @@ func testCacheInsertAndReplace(t *testing.T, cache Interface[int, int]) {
 	assertCacheLookup(t, cache, 1, 11, true)
 }
 
+func testCacheLookupOrInsert(t *testing.T, cache Interface[int, int]) {
+	if v, loaded := cache.LookupOrInsert(1, 10); loaded {
+		t.Error("looking up a non-existing key reported a loaded value")
+	} else if v != 10 {
+		t.Errorf("wrong value returned when inserting: got=%v want=10", v)
+	}
+
+	if v, loaded := cache.LookupOrInsert(1, 11); !loaded {
+		t.Error("looking up an existing key did not report a loaded value")
+	} else if v != 10 {
+		t.Errorf("wrong value returned when loading: got=%v want=10", v)
+	}
+
+	if n := cache.Len(); n != 1 {
+		t.Errorf("wrong number of cache entries: got=%d want=1", n)
+	}
+	assertCacheLookup(t, cache, 1, 10, true)
+}
+
 func assertCacheLookup(t *testing.T, cache Interface[int, int], key, value int, ok bool) {
 	t.Helper()
 	v, found := cache.Lookup(key)
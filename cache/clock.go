@@ -0,0 +1,133 @@
+package cache
+
+// Clock is an Interface implementation of the clock (second-chance) eviction
+// algorithm, which approximates LRU with less bookkeeping on lookups.
+//
+// Entries are held in a circular buffer, each with a reference bit set when the
+// entry is looked up. Evict advances a hand around the buffer, clearing the
+// reference bits of the entries it passes until it finds an entry which was
+// not referenced since the previous sweep. Unlike LRU, lookups only set a bit
+// and never relink entries.
+//
+// Unlike RingCache, the buffer grows as entries are inserted, the capacity of
+// the cache is controlled by the program calling Evict.
+type Clock[K comparable, V any] struct {
+	index map[K]int
+	slots []clockSlot[K, V]
+	free  []int
+	hand  int
+}
+
+type clockSlot[K comparable, V any] struct {
+	entry[K, V]
+	used       bool
+	referenced bool
+}
+
+func (c *Clock[K, V]) Len() int {
+	return len(c.index)
+}
+
+func (c *Clock[K, V]) Insert(key K, value V) (previous V, replaced bool) {
+	if c.index == nil {
+		c.index = make(map[K]int)
+	}
+	if i, ok := c.index[key]; ok {
+		slot := &c.slots[i]
+		previous, replaced = slot.value, true
+		slot.value = value
+		slot.referenced = true
+		return previous, replaced
+	}
+
+	slot := clockSlot[K, V]{entry: entry[K, V]{key: key, value: value}, used: true}
+	if n := len(c.free); n != 0 {
+		i := c.free[n-1]
+		c.free = c.free[:n-1]
+		c.slots[i] = slot
+		c.index[key] = i
+	} else {
+		c.index[key] = len(c.slots)
+		c.slots = append(c.slots, slot)
+	}
+	return previous, replaced
+}
+
+func (c *Clock[K, V]) Lookup(key K) (value V, found bool) {
+	i, ok := c.index[key]
+	if ok {
+		slot := &c.slots[i]
+		slot.referenced = true
+		value, found = slot.value, true
+	}
+	return value, found
+}
+
+// Peek returns the value associated with key without setting the reference
+// bit of the entry.
+func (c *Clock[K, V]) Peek(key K) (value V, found bool) {
+	i, ok := c.index[key]
+	if ok {
+		value, found = c.slots[i].value, true
+	}
+	return value, found
+}
+
+func (c *Clock[K, V]) LookupOrInsert(key K, value V) (actual V, loaded bool) {
+	if actual, loaded = c.Lookup(key); loaded {
+		return actual, loaded
+	}
+	c.Insert(key, value)
+	return value, false
+}
+
+func (c *Clock[K, V]) Delete(key K) (value V, deleted bool) {
+	i, ok := c.index[key]
+	if ok {
+		value, deleted = c.slots[i].value, true
+		c.remove(i)
+	}
+	return value, deleted
+}
+
+// Evict removes the first entry found by the clock hand which was not
+// referenced since the hand last passed it.
+func (c *Clock[K, V]) Evict() (key K, value V, evicted bool) {
+	if len(c.index) == 0 {
+		return key, value, false
+	}
+	for {
+		if c.hand >= len(c.slots) {
+			c.hand = 0
+		}
+		i := c.hand
+		c.hand++
+
+		slot := &c.slots[i]
+		if !slot.used {
+			continue
+		}
+		if slot.referenced {
+			slot.referenced = false
+			continue
+		}
+
+		key, value, evicted = slot.key, slot.value, true
+		c.remove(i)
+		return key, value, evicted
+	}
+}
+
+func (c *Clock[K, V]) Range(f func(K, V) bool) {
+	for i := range c.slots {
+		if slot := &c.slots[i]; slot.used && !f(slot.key, slot.value) {
+			break
+		}
+	}
+}
+
+func (c *Clock[K, V]) remove(i int) {
+	delete(c.index, c.slots[i].key)
+	c.slots[i] = clockSlot[K, V]{}
+	c.free = append(c.free, i)
+}
@@ -0,0 +1,144 @@
+package cache
+
+import "sort"
+
+// Instrumented wraps an underlying caching implementation, counting the hits
+// of each key to help detect hot keys.
+//
+// Only keys resident in the cache are tracked: the counter of a key is dropped
+// when the key is deleted or evicted, which bounds the memory used by the
+// counters to the size of the cache.
+//
+// By default, a LRU caching strategy is used.
+type Instrumented[K comparable, V any] struct {
+	backend Interface[K, V]
+	hits    map[K]int64
+}
+
+// KeyHits is a key of a cache paired with the number of hits it received.
+type KeyHits[K comparable] struct {
+	Key  K
+	Hits int64
+}
+
+func (c *Instrumented[K, V]) Init(backend Interface[K, V]) {
+	c.backend = backend
+	c.hits = nil
+}
+
+// Hits returns the number of hits recorded for key since it was inserted in
+// the cache.
+func (c *Instrumented[K, V]) Hits(key K) int64 {
+	return c.hits[key]
+}
+
+// TopKeys returns up to n keys of the cache with the most hits, in descending
+// order of hits. Keys which never received a hit are not returned.
+//
+// The tracked keys are looked up with Peek if the backend supports it,
+// otherwise the entries of the cache are scanned once with Range.
+func (c *Instrumented[K, V]) TopKeys(n int) []KeyHits[K] {
+	if n <= 0 || len(c.hits) == 0 {
+		return nil
+	}
+	top := make([]KeyHits[K], 0, len(c.hits))
+
+	// Backends may evict entries without going through Evict (e.g. RingCache
+	// when it is full), so counters of keys which are not in the cache
+	// anymore are cleaned up here.
+	if p, ok := c.backend.(interface{ Peek(K) (V, bool) }); ok {
+		for key, hits := range c.hits {
+			if _, found := p.Peek(key); !found {
+				delete(c.hits, key)
+				continue
+			}
+			top = append(top, KeyHits[K]{Key: key, Hits: hits})
+		}
+	} else {
+		resident := make(map[K]int64, len(c.hits))
+		c.backend.Range(func(key K, _ V) bool {
+			if hits, ok := c.hits[key]; ok {
+				resident[key] = hits
+				top = append(top, KeyHits[K]{Key: key, Hits: hits})
+			}
+			return true
+		})
+		c.hits = resident
+	}
+
+	sort.Slice(top, func(i, j int) bool { return top[i].Hits > top[j].Hits })
+	if len(top) > n {
+		top = top[:n]
+	}
+	return top
+}
+
+func (c *Instrumented[K, V]) Len() int {
+	if c.backend != nil {
+		return c.backend.Len()
+	}
+	return 0
+}
+
+func (c *Instrumented[K, V]) Insert(key K, value V) (previous V, replaced bool) {
+	if c.backend == nil {
+		c.backend = new(LRU[K, V])
+	}
+	if previous, replaced = c.backend.Insert(key, value); !replaced {
+		// The key may have been tracked in a previous life of the cache
+		// entry if the backend evicted it implicitly.
+		delete(c.hits, key)
+	}
+	return previous, replaced
+}
+
+func (c *Instrumented[K, V]) Lookup(key K) (value V, found bool) {
+	if c.backend != nil {
+		if value, found = c.backend.Lookup(key); found {
+			c.hit(key)
+		}
+	}
+	return value, found
+}
+
+func (c *Instrumented[K, V]) LookupOrInsert(key K, value V) (actual V, loaded bool) {
+	if c.backend == nil {
+		c.backend = new(LRU[K, V])
+	}
+	if actual, loaded = c.backend.LookupOrInsert(key, value); loaded {
+		c.hit(key)
+	} else {
+		delete(c.hits, key)
+	}
+	return actual, loaded
+}
+
+func (c *Instrumented[K, V]) Delete(key K) (value V, deleted bool) {
+	if c.backend != nil {
+		value, deleted = c.backend.Delete(key)
+		delete(c.hits, key)
+	}
+	return value, deleted
+}
+
+func (c *Instrumented[K, V]) Evict() (key K, value V, evicted bool) {
+	if c.backend != nil {
+		if key, value, evicted = c.backend.Evict(); evicted {
+			delete(c.hits, key)
+		}
+	}
+	return key, value, evicted
+}
+
+func (c *Instrumented[K, V]) Range(f func(K, V) bool) {
+	if c.backend != nil {
+		c.backend.Range(f)
+	}
+}
+
+func (c *Instrumented[K, V]) hit(key K) {
+	if c.hits == nil {
+		c.hits = make(map[K]int64)
+	}
+	c.hits[key]++
+}
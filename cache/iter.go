@@ -0,0 +1,16 @@
+//go:build go1.23
+
+package cache
+
+import "iter"
+
+// EvictionOrder returns an iterator over the entries of the cache, from the
+// least to the most recently used, without removing them or affecting their
+// order.
+//
+// The loop body may delete the entry yielded by the iterator, which allows
+// programs to select the entries to evict by inspecting them in the order they
+// would be evicted, but must not otherwise modify the cache.
+func (lru *LRU[K, V]) EvictionOrder() iter.Seq2[K, V] {
+	return lru.RangeEvictionOrder
+}
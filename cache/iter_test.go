@@ -0,0 +1,63 @@
+//go:build go1.23
+
+package cache
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestLRUEvictionOrder(t *testing.T) {
+	lru := new(LRU[int, int])
+	for i := 0; i < 6; i++ {
+		lru.Insert(i, 10*i)
+	}
+	lru.Lookup(0)
+
+	keys := []int{}
+	for k, v := range lru.EvictionOrder() {
+		if v != 10*k {
+			t.Errorf("wrong value for key=%d: got=%d want=%d", k, v, 10*k)
+		}
+		keys = append(keys, k)
+	}
+	if fmt.Sprint(keys) != "[1 2 3 4 5 0]" {
+		t.Errorf("wrong eviction order: got=%v want=[1 2 3 4 5 0]", keys)
+	}
+
+	// Delete the odd keys among the first four candidates, the iteration
+	// must continue with the entries that follow the deleted ones.
+	keys = keys[:0]
+	for k := range lru.EvictionOrder() {
+		if k%2 != 0 {
+			lru.Delete(k)
+		}
+		if keys = append(keys, k); len(keys) == 4 {
+			break
+		}
+	}
+	if fmt.Sprint(keys) != "[1 2 3 4]" {
+		t.Errorf("wrong keys visited while deleting: got=%v want=[1 2 3 4]", keys)
+	}
+	if lru.Len() != 4 {
+		t.Errorf("wrong number of cache entries: got=%d want=4", lru.Len())
+	}
+	for _, want := range []int{2, 4, 5, 0} {
+		if k, _, _ := lru.Evict(); k != want {
+			t.Errorf("wrong key evicted: got=%d want=%d", k, want)
+		}
+	}
+
+	// Deleting every entry during the iteration empties the cache.
+	for i := 0; i < 6; i++ {
+		lru.Insert(i, 10*i)
+	}
+	n := 0
+	for k := range lru.EvictionOrder() {
+		lru.Delete(k)
+		n++
+	}
+	if n != 6 || lru.Len() != 0 {
+		t.Errorf("wrong result of deleting all entries: visited=%d len=%d want=6,0", n, lru.Len())
+	}
+}
@@ -1,17 +1,33 @@
 package cache
 
-import "github.com/segmentio/datastructures/v2/container/list"
+import (
+	"unsafe"
+
+	"github.com/segmentio/datastructures/v2/container/list"
+)
 
 // LRU is an Interface implementation which caches elements and tracks least
 // recently used items as candidates for eviction.
+//
+// Keys can be protected from eviction by calling Protect, in which case Evict
+// skips them and selects the least recently used entry which is not protected.
+//
+// By default, entries are moved to the front of the queue on every lookup; a
+// promotion policy can be installed with SetPromotionPolicy to make it less
+// aggressive.
 type LRU[K comparable, V any] struct {
-	index map[K]*list.Element[entry[K, V]]
-	queue list.List[entry[K, V]]
+	index     map[K]*list.Element[entry[K, V]]
+	queue     list.List[entry[K, V]]
+	protected map[K]struct{}
+	promote   func(hits int) bool
 }
 
 type entry[K comparable, V any] struct {
 	key   K
 	value V
+	// Number of lookups since the entry was last moved to the front of the
+	// LRU queue, used by promotion policies.
+	hits int
 }
 
 func (lru *LRU[K, V]) Len() int {
@@ -26,6 +42,7 @@ func (lru *LRU[K, V]) Insert(key K, value V) (previous V, replaced bool) {
 	if ok {
 		previous, replaced = e.Value.value, true
 		e.Value.value = value
+		e.Value.hits = 0
 		lru.queue.MoveToFront(e)
 	} else {
 		lru.index[key] = lru.queue.PushFront(entry[K, V]{key: key, value: value})
@@ -33,15 +50,72 @@ func (lru *LRU[K, V]) Insert(key K, value V) (previous V, replaced bool) {
 	return previous, replaced
 }
 
+// BulkInsert inserts a batch of entries in the cache, as if Insert had been
+// called for each of them in order. The index of the cache is sized for the
+// whole batch before inserting the entries, avoiding repeated growth when
+// warming up the cache.
+func (lru *LRU[K, V]) BulkInsert(entries []Entry[K, V]) {
+	if size := len(lru.index) + len(entries); lru.index == nil || len(entries) > len(lru.index) {
+		index := make(map[K]*list.Element[entry[K, V]], size)
+		for k, e := range lru.index {
+			index[k] = e
+		}
+		lru.index = index
+	}
+	for _, e := range entries {
+		lru.Insert(e.Key, e.Value)
+	}
+}
+
+// SetPromotionPolicy installs a function deciding whether lookups move entries
+// to the front of the queue. The function is called on each lookup hit with
+// the number of hits since the entry was inserted or last promoted (starting
+// at one), and the entry is promoted if it returns true. For example, a policy
+// returning hits >= 2 only promotes entries on their second access, which
+// approximates the behavior of a segmented LRU.
+//
+// Setting the policy to nil restores the default behavior of promoting entries
+// on every lookup. Inserting a value for an existing key always promotes the
+// entry.
+func (lru *LRU[K, V]) SetPromotionPolicy(promote func(hits int) bool) {
+	lru.promote = promote
+}
+
 func (lru *LRU[K, V]) Lookup(key K) (value V, found bool) {
 	e, ok := lru.index[key]
 	if ok {
-		lru.queue.MoveToFront(e)
+		lru.hit(e)
 		value, found = e.Value.value, true
 	}
 	return value, found
 }
 
+// Peek returns the value associated with key without moving the entry to the
+// front of the queue.
+func (lru *LRU[K, V]) Peek(key K) (value V, found bool) {
+	e, ok := lru.index[key]
+	if ok {
+		value, found = e.Value.value, true
+	}
+	return value, found
+}
+
+func (lru *LRU[K, V]) LookupOrInsert(key K, value V) (actual V, loaded bool) {
+	if e, ok := lru.index[key]; ok {
+		lru.hit(e)
+		return e.Value.value, true
+	}
+	lru.Insert(key, value)
+	return value, false
+}
+
+func (lru *LRU[K, V]) hit(e *list.Element[entry[K, V]]) {
+	if e.Value.hits++; lru.promote == nil || lru.promote(e.Value.hits) {
+		e.Value.hits = 0
+		lru.queue.MoveToFront(e)
+	}
+}
+
 func (lru *LRU[K, V]) Delete(key K) (value V, deleted bool) {
 	e, ok := lru.index[key]
 	if ok {
@@ -52,9 +126,52 @@ func (lru *LRU[K, V]) Delete(key K) (value V, deleted bool) {
 	return value, deleted
 }
 
+// MemoryUsage returns an estimate of the memory footprint of the cache, in
+// bytes.
+//
+// The estimate accounts for the list elements and index entries of the cache,
+// assuming that each index entry costs the size of a key and a pointer. Memory
+// referenced by the keys and values (e.g. the bytes of string keys) is not
+// included.
+func (lru *LRU[K, V]) MemoryUsage() int64 {
+	var key K
+	var elem list.Element[entry[K, V]]
+	size := int64(unsafe.Sizeof(*lru))
+	size += int64(lru.queue.Len()) * int64(unsafe.Sizeof(elem)+unsafe.Sizeof(key)+unsafe.Sizeof(&elem))
+	size += int64(len(lru.protected)) * int64(unsafe.Sizeof(key))
+	return size
+}
+
+// Protect prevents the entry associated with key from being evicted. The key
+// does not need to exist in the cache, it remains protected until a call to
+// Unprotect, even if the entry is deleted and inserted again.
+func (lru *LRU[K, V]) Protect(key K) {
+	if lru.protected == nil {
+		lru.protected = make(map[K]struct{})
+	}
+	lru.protected[key] = struct{}{}
+}
+
+// Unprotect makes the entry associated with key a candidate for eviction
+// again.
+func (lru *LRU[K, V]) Unprotect(key K) {
+	delete(lru.protected, key)
+}
+
+// Evict removes the least recently used entry which is not protected from the
+// cache. The method returns evicted=false if the cache is empty or all its
+// entries are protected.
 func (lru *LRU[K, V]) Evict() (key K, value V, evicted bool) {
-	if lru.queue.Len() > 0 {
-		e := lru.queue.Back()
+	e := lru.queue.Back()
+	if len(lru.protected) != 0 {
+		for e != nil {
+			if _, skip := lru.protected[e.Value.key]; !skip {
+				break
+			}
+			e = e.Prev()
+		}
+	}
+	if e != nil {
 		lru.queue.Remove(e)
 		delete(lru.index, e.Value.key)
 		key, value, evicted = e.Value.key, e.Value.value, true
@@ -69,3 +186,21 @@ func (lru *LRU[K, V]) Range(f func(K, V) bool) {
 		}
 	}
 }
+
+// RangeEvictionOrder calls f for each entry in the cache, from the least to the
+// most recently used, without removing them or affecting their order. If f
+// returns false, iteration stops.
+//
+// This allows programs to implement custom eviction policies by inspecting the
+// entries in the order they would be evicted, and deleting the ones they
+// select. The callback may delete the entry it was called with, but must not
+// otherwise modify the cache.
+func (lru *LRU[K, V]) RangeEvictionOrder(f func(K, V) bool) {
+	for e := lru.queue.Back(); e != nil; {
+		prev := e.Prev()
+		if !f(e.Value.key, e.Value.value) {
+			break
+		}
+		e = prev
+	}
+}
@@ -0,0 +1,51 @@
+package cache
+
+// MRU is an Interface implementation which caches elements and evicts the most
+// recently used items first.
+//
+// Evicting the most recent entries is counterintuitive, but optimal for cyclic
+// access patterns, for example repeated scans of a data set larger than the
+// cache, where the oldest entries are the next ones to be accessed again.
+type MRU[K comparable, V any] struct {
+	lru LRU[K, V]
+}
+
+func (mru *MRU[K, V]) Len() int {
+	return mru.lru.Len()
+}
+
+func (mru *MRU[K, V]) Insert(key K, value V) (previous V, replaced bool) {
+	return mru.lru.Insert(key, value)
+}
+
+func (mru *MRU[K, V]) Lookup(key K) (value V, found bool) {
+	return mru.lru.Lookup(key)
+}
+
+// Peek returns the value associated with key without moving the entry to the
+// front of the queue.
+func (mru *MRU[K, V]) Peek(key K) (value V, found bool) {
+	return mru.lru.Peek(key)
+}
+
+func (mru *MRU[K, V]) LookupOrInsert(key K, value V) (actual V, loaded bool) {
+	return mru.lru.LookupOrInsert(key, value)
+}
+
+func (mru *MRU[K, V]) Delete(key K) (value V, deleted bool) {
+	return mru.lru.Delete(key)
+}
+
+// Evict removes the most recently used entry from the cache.
+func (mru *MRU[K, V]) Evict() (key K, value V, evicted bool) {
+	if e := mru.lru.queue.Front(); e != nil {
+		mru.lru.queue.Remove(e)
+		delete(mru.lru.index, e.Value.key)
+		key, value, evicted = e.Value.key, e.Value.value, true
+	}
+	return key, value, evicted
+}
+
+func (mru *MRU[K, V]) Range(f func(K, V) bool) {
+	mru.lru.Range(f)
+}
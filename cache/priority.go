@@ -0,0 +1,141 @@
+package cache
+
+import "github.com/segmentio/datastructures/v2/container/tree"
+
+// PriorityCache is a cache which associates a priority with each entry, and
+// evicts the entries with the lowest priorities first.
+//
+// The priorities are ordered by the comparison function passed to Init or
+// NewPriorityCache. Entries with equal priorities are evicted in the order in
+// which they were given their priority. This generalizes LRU or LFU policies to
+// arbitrary eviction scores.
+type PriorityCache[K comparable, P any, V any] struct {
+	index map[K]*priorityEntry[K, P, V]
+	queue tree.Map[priority[P], *priorityEntry[K, P, V]]
+	seq   uint64
+}
+
+type priority[P any] struct {
+	value P
+	seq   uint64
+}
+
+type priorityEntry[K comparable, P any, V any] struct {
+	key      K
+	value    V
+	priority priority[P]
+}
+
+// NewPriorityCache constructs a new priority cache using the comparison
+// function passed as argument to order the priorities of entries.
+func NewPriorityCache[K comparable, P any, V any](cmp func(P, P) int) *PriorityCache[K, P, V] {
+	c := new(PriorityCache[K, P, V])
+	c.Init(cmp)
+	return c
+}
+
+// Init initializes (or re-initializes) the cache with the given comparison
+// function to order the priorities of entries.
+func (c *PriorityCache[K, P, V]) Init(cmp func(P, P) int) {
+	c.index = make(map[K]*priorityEntry[K, P, V])
+	c.queue.Init(func(p1, p2 priority[P]) int {
+		if cmp := cmp(p1.value, p2.value); cmp != 0 {
+			return cmp
+		}
+		switch {
+		case p1.seq < p2.seq:
+			return -1
+		case p1.seq > p2.seq:
+			return +1
+		default:
+			return 0
+		}
+	})
+	c.seq = 0
+}
+
+func (c *PriorityCache[K, P, V]) Len() int {
+	return len(c.index)
+}
+
+// Insert inserts an entry with the given priority in the cache, or replaces
+// the value and priority of an existing entry.
+//
+// The cache must have been initialized by a call to NewPriorityCache or Init or
+// the call to Insert will panic.
+func (c *PriorityCache[K, P, V]) Insert(key K, priority P, value V) (previous V, replaced bool) {
+	e, ok := c.index[key]
+	if ok {
+		previous, replaced = e.value, true
+		e.value = value
+		c.queue.Delete(e.priority)
+	} else {
+		e = &priorityEntry[K, P, V]{key: key, value: value}
+		c.index[key] = e
+	}
+	c.push(e, priority)
+	return previous, replaced
+}
+
+// UpdatePriority changes the priority of the entry associated with key. The
+// method returns false if the key did not exist in the cache.
+func (c *PriorityCache[K, P, V]) UpdatePriority(key K, priority P) (updated bool) {
+	e, ok := c.index[key]
+	if ok {
+		c.queue.Delete(e.priority)
+		c.push(e, priority)
+	}
+	return ok
+}
+
+// Priority returns the priority of the entry associated with key.
+func (c *PriorityCache[K, P, V]) Priority(key K) (priority P, found bool) {
+	e, ok := c.index[key]
+	if ok {
+		priority, found = e.priority.value, true
+	}
+	return priority, found
+}
+
+func (c *PriorityCache[K, P, V]) Lookup(key K) (value V, found bool) {
+	e, ok := c.index[key]
+	if ok {
+		value, found = e.value, true
+	}
+	return value, found
+}
+
+func (c *PriorityCache[K, P, V]) Delete(key K) (value V, deleted bool) {
+	e, ok := c.index[key]
+	if ok {
+		delete(c.index, key)
+		c.queue.Delete(e.priority)
+		value, deleted = e.value, true
+	}
+	return value, deleted
+}
+
+// Evict removes the entry with the lowest priority from the cache.
+func (c *PriorityCache[K, P, V]) Evict() (key K, value V, evicted bool) {
+	_, e, ok := c.queue.Min()
+	if ok {
+		delete(c.index, e.key)
+		c.queue.Delete(e.priority)
+		key, value, evicted = e.key, e.value, true
+	}
+	return key, value, evicted
+}
+
+func (c *PriorityCache[K, P, V]) Range(f func(K, V) bool) {
+	for _, e := range c.index {
+		if !f(e.key, e.value) {
+			break
+		}
+	}
+}
+
+func (c *PriorityCache[K, P, V]) push(e *priorityEntry[K, P, V], value P) {
+	c.seq++
+	e.priority = priority[P]{value: value, seq: c.seq}
+	c.queue.Insert(e.priority, e)
+}
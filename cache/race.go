@@ -0,0 +1,97 @@
+package cache
+
+import "sync/atomic"
+
+// RaceChecked wraps an underlying caching implementation, detecting concurrent
+// use of the cache.
+//
+// Each method marks the cache as in use for the duration of the call, and
+// panics if it was already in use, which happens when two goroutines access
+// the cache at the same time. This is intended to surface accidental
+// concurrent use of the caches of this package during development; detection
+// is best effort since calls that do not overlap in time are not reported,
+// the race detector remains the authoritative tool.
+//
+// By default, a LRU caching strategy is used.
+type RaceChecked[K comparable, V any] struct {
+	inuse   int32
+	backend Interface[K, V]
+}
+
+func (c *RaceChecked[K, V]) Init(backend Interface[K, V]) {
+	c.backend = backend
+}
+
+func (c *RaceChecked[K, V]) enter(method string) {
+	if !atomic.CompareAndSwapInt32(&c.inuse, 0, 1) {
+		panic("cache: concurrent call to " + method + " while the cache is in use by another goroutine")
+	}
+}
+
+func (c *RaceChecked[K, V]) exit() {
+	atomic.StoreInt32(&c.inuse, 0)
+}
+
+func (c *RaceChecked[K, V]) Len() int {
+	c.enter("Len")
+	defer c.exit()
+	if c.backend != nil {
+		return c.backend.Len()
+	}
+	return 0
+}
+
+func (c *RaceChecked[K, V]) Insert(key K, value V) (previous V, replaced bool) {
+	c.enter("Insert")
+	defer c.exit()
+	if c.backend == nil {
+		c.backend = new(LRU[K, V])
+	}
+	return c.backend.Insert(key, value)
+}
+
+func (c *RaceChecked[K, V]) Lookup(key K) (value V, found bool) {
+	c.enter("Lookup")
+	defer c.exit()
+	if c.backend != nil {
+		value, found = c.backend.Lookup(key)
+	}
+	return value, found
+}
+
+func (c *RaceChecked[K, V]) LookupOrInsert(key K, value V) (actual V, loaded bool) {
+	c.enter("LookupOrInsert")
+	defer c.exit()
+	if c.backend == nil {
+		c.backend = new(LRU[K, V])
+	}
+	return c.backend.LookupOrInsert(key, value)
+}
+
+func (c *RaceChecked[K, V]) Delete(key K) (value V, deleted bool) {
+	c.enter("Delete")
+	defer c.exit()
+	if c.backend != nil {
+		value, deleted = c.backend.Delete(key)
+	}
+	return value, deleted
+}
+
+func (c *RaceChecked[K, V]) Evict() (key K, value V, evicted bool) {
+	c.enter("Evict")
+	defer c.exit()
+	if c.backend != nil {
+		key, value, evicted = c.backend.Evict()
+	}
+	return key, value, evicted
+}
+
+// Range calls f for each entry in the cache. The cache is in use during the
+// iteration, so f must not call methods of the cache.
+func (c *RaceChecked[K, V]) Range(f func(K, V) bool) {
+	c.enter("Range")
+	defer c.exit()
+	if c.backend != nil {
+		c.backend.Range(f)
+	}
+}
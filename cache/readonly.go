@@ -0,0 +1,54 @@
+package cache
+
+// ReadOnly is a read-only view of a cache, exposing methods which do not
+// modify the cache or the eviction order of its entries. It is intended to be
+// handed to components of a program which must not mutate the cache.
+//
+// ReadOnly values are created by calling Freeze.
+type ReadOnly[K comparable, V any] struct {
+	backend Interface[K, V]
+}
+
+// Freeze returns a read-only view of the cache passed as argument. The view
+// reflects changes made to the underlying cache.
+func Freeze[K comparable, V any](backend Interface[K, V]) ReadOnly[K, V] {
+	return ReadOnly[K, V]{backend: backend}
+}
+
+// Len returns the number of entries in the cache.
+func (r ReadOnly[K, V]) Len() int {
+	if r.backend != nil {
+		return r.backend.Len()
+	}
+	return 0
+}
+
+// Peek returns the value associated with key in the cache, without affecting
+// the eviction order of entries. If the cache does not implement a Peek method,
+// the entries are scanned with Range to find the key.
+func (r ReadOnly[K, V]) Peek(key K) (value V, found bool) {
+	if r.backend != nil {
+		value, found = peek(r.backend, key)
+	}
+	return value, found
+}
+
+// Range calls f for each entry in the cache.
+func (r ReadOnly[K, V]) Range(f func(K, V) bool) {
+	if r.backend != nil {
+		r.backend.Range(f)
+	}
+}
+
+func peek[K comparable, V any](backend Interface[K, V], key K) (value V, found bool) {
+	if p, ok := backend.(interface{ Peek(K) (V, bool) }); ok {
+		return p.Peek(key)
+	}
+	backend.Range(func(k K, v V) bool {
+		if k == key {
+			value, found = v, true
+		}
+		return !found
+	})
+	return value, found
+}
@@ -0,0 +1,128 @@
+package cache
+
+import "sync"
+
+// RefCounted is an Interface implementation of a LRU cache which tracks
+// references to its entries, and never evicts entries that are still in use.
+//
+// Each call to Lookup or LookupOrInsert acquires a reference to the entry,
+// which must be released by a call to Release when the program is done using
+// the value. Evict selects the least recently used entry which has no
+// references, making the cache suitable to hold shared resources such as open
+// connections or file handles.
+//
+// Unlike the other types of this package, RefCounted is safe to use
+// concurrently from multiple goroutines, since references are usually
+// acquired and released by different parts of a program.
+type RefCounted[K comparable, V any] struct {
+	mutex sync.Mutex
+	cache LRU[K, V]
+	refs  map[K]int
+}
+
+func (rc *RefCounted[K, V]) Len() int {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	return rc.cache.Len()
+}
+
+func (rc *RefCounted[K, V]) Insert(key K, value V) (previous V, replaced bool) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	return rc.cache.Insert(key, value)
+}
+
+// Lookup returns the value associated with key, acquiring a reference to the
+// entry if it was found.
+func (rc *RefCounted[K, V]) Lookup(key K) (value V, found bool) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	value, found = rc.cache.Lookup(key)
+	if found {
+		rc.acquire(key)
+	}
+	return value, found
+}
+
+// Peek returns the value associated with key without acquiring a reference
+// to the entry or affecting the eviction order of entries.
+func (rc *RefCounted[K, V]) Peek(key K) (value V, found bool) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	return rc.cache.Peek(key)
+}
+
+// LookupOrInsert returns the value associated with key, or inserts it, and
+// acquires a reference to the entry in both cases.
+func (rc *RefCounted[K, V]) LookupOrInsert(key K, value V) (actual V, loaded bool) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	actual, loaded = rc.cache.LookupOrInsert(key, value)
+	rc.acquire(key)
+	return actual, loaded
+}
+
+// Release releases a reference to the entry associated with key, previously
+// acquired by a call to Lookup or LookupOrInsert. Once all references have
+// been released, the entry can be evicted again. The method returns the number
+// of references remaining.
+func (rc *RefCounted[K, V]) Release(key K) (refs int) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	refs, ok := rc.refs[key]
+	if !ok {
+		return 0
+	}
+	if refs--; refs == 0 {
+		delete(rc.refs, key)
+		rc.cache.Unprotect(key)
+	} else {
+		rc.refs[key] = refs
+	}
+	return refs
+}
+
+// Refs returns the number of references held on the entry associated with key.
+func (rc *RefCounted[K, V]) Refs(key K) int {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	return rc.refs[key]
+}
+
+// Delete removes the entry associated with key from the cache. References held
+// on the entry are not affected, they protect the entry from eviction if it is
+// inserted again before they are released.
+func (rc *RefCounted[K, V]) Delete(key K) (value V, deleted bool) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	return rc.cache.Delete(key)
+}
+
+// Evict removes the least recently used entry which has no references from the
+// cache. The method returns evicted=false if the cache is empty or all its
+// entries are referenced.
+func (rc *RefCounted[K, V]) Evict() (key K, value V, evicted bool) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	return rc.cache.Evict()
+}
+
+// Range calls f for each entry in the cache. The cache is locked during the
+// iteration, so f must not call methods of the cache.
+func (rc *RefCounted[K, V]) Range(f func(K, V) bool) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	rc.cache.Range(f)
+}
+
+func (rc *RefCounted[K, V]) acquire(key K) {
+	if rc.refs == nil {
+		rc.refs = make(map[K]int)
+	}
+	if rc.refs[key]++; rc.refs[key] == 1 {
+		rc.cache.Protect(key)
+	}
+}
@@ -0,0 +1,194 @@
+package cache
+
+// RingCache is an Interface implementation of a fixed-capacity cache which
+// never allocates memory after being constructed.
+//
+// Entries are stored in a preallocated array of slots indexed by an open
+// addressing hash table, and evictions use the clock algorithm: each entry has
+// a reference bit set when it is looked up, and the clock hand sweeps through
+// the slots, clearing reference bits until it finds an entry which was not
+// referenced since the last sweep.
+//
+// Since the cache has a fixed capacity, inserting a new key when the cache is
+// full evicts an entry to make room for the new one. Such implicit evictions
+// are not reported to the caller, programs which need to observe evictions
+// should call Evict before inserting keys when Len reaches the capacity.
+//
+// RingCache values must be constructed by a call to NewRingCache.
+type RingCache[K comparable, V any] struct {
+	hash  func(K) uint64
+	slots []ringSlot[K, V]
+	free  []int32 // stack of indexes of unused slots
+	table []int32 // indexes of used slots plus one, zero for empty buckets
+	hand  int
+}
+
+type ringSlot[K comparable, V any] struct {
+	entry[K, V]
+	hash       uint64
+	used       bool
+	referenced bool
+}
+
+// NewRingCache constructs a cache holding up to capacity entries, using the
+// hash function passed as argument to index the keys.
+func NewRingCache[K comparable, V any](capacity int, hash func(K) uint64) *RingCache[K, V] {
+	if capacity < 1 {
+		capacity = 1
+	}
+	tableSize := 2
+	for tableSize < 2*capacity {
+		tableSize *= 2
+	}
+	c := &RingCache[K, V]{
+		hash:  hash,
+		slots: make([]ringSlot[K, V], capacity),
+		free:  make([]int32, capacity),
+		table: make([]int32, tableSize),
+	}
+	for i := range c.free {
+		c.free[i] = int32(capacity - (i + 1))
+	}
+	return c
+}
+
+// Cap returns the maximum number of entries that the cache can hold.
+func (c *RingCache[K, V]) Cap() int {
+	return len(c.slots)
+}
+
+func (c *RingCache[K, V]) Len() int {
+	return len(c.slots) - len(c.free)
+}
+
+func (c *RingCache[K, V]) Insert(key K, value V) (previous V, replaced bool) {
+	h := c.hash(key)
+	b, s := c.find(key, h)
+	if s >= 0 {
+		slot := &c.slots[s]
+		previous, replaced = slot.value, true
+		slot.value = value
+		slot.referenced = true
+		return previous, replaced
+	}
+
+	if len(c.free) == 0 {
+		c.Evict()
+		// The eviction may have shifted entries of the hash table, so the
+		// bucket where the key is inserted must be located again.
+		b, _ = c.find(key, h)
+	}
+
+	s = int(c.free[len(c.free)-1])
+	c.free = c.free[:len(c.free)-1]
+	c.slots[s] = ringSlot[K, V]{
+		entry: entry[K, V]{key: key, value: value},
+		hash:  h,
+		used:  true,
+	}
+	c.table[b] = int32(s + 1)
+	return previous, replaced
+}
+
+func (c *RingCache[K, V]) Lookup(key K) (value V, found bool) {
+	if _, s := c.find(key, c.hash(key)); s >= 0 {
+		slot := &c.slots[s]
+		slot.referenced = true
+		value, found = slot.value, true
+	}
+	return value, found
+}
+
+// Peek returns the value associated with key without setting the reference
+// bit of the entry.
+func (c *RingCache[K, V]) Peek(key K) (value V, found bool) {
+	if _, s := c.find(key, c.hash(key)); s >= 0 {
+		value, found = c.slots[s].value, true
+	}
+	return value, found
+}
+
+func (c *RingCache[K, V]) LookupOrInsert(key K, value V) (actual V, loaded bool) {
+	if actual, loaded = c.Lookup(key); loaded {
+		return actual, loaded
+	}
+	c.Insert(key, value)
+	return value, false
+}
+
+func (c *RingCache[K, V]) Delete(key K) (value V, deleted bool) {
+	if b, s := c.find(key, c.hash(key)); s >= 0 {
+		value, deleted = c.slots[s].value, true
+		c.remove(b, s)
+	}
+	return value, deleted
+}
+
+// Evict removes an entry selected by the clock algorithm from the cache.
+func (c *RingCache[K, V]) Evict() (key K, value V, evicted bool) {
+	if c.Len() == 0 {
+		return key, value, false
+	}
+	for {
+		s := c.hand
+		c.hand = (c.hand + 1) % len(c.slots)
+
+		slot := &c.slots[s]
+		if !slot.used {
+			continue
+		}
+		if slot.referenced {
+			slot.referenced = false
+			continue
+		}
+
+		key, value, evicted = slot.key, slot.value, true
+		b, _ := c.find(key, slot.hash)
+		c.remove(b, s)
+		return key, value, evicted
+	}
+}
+
+func (c *RingCache[K, V]) Range(f func(K, V) bool) {
+	for i := range c.slots {
+		if slot := &c.slots[i]; slot.used && !f(slot.key, slot.value) {
+			break
+		}
+	}
+}
+
+// find returns the bucket of the hash table where key is or would be stored,
+// and the index of the slot holding the key, or -1 if it was not found.
+func (c *RingCache[K, V]) find(key K, h uint64) (bucket, slot int) {
+	mask := uint64(len(c.table) - 1)
+	for b := h & mask; ; b = (b + 1) & mask {
+		s := int(c.table[b]) - 1
+		if s < 0 || c.slots[s].key == key {
+			return int(b), s
+		}
+	}
+}
+
+// remove releases slot s indexed in bucket b of the hash table. Entries
+// following the bucket are shifted back so that lookups never need to probe
+// past empty buckets, which avoids the use of tombstones.
+func (c *RingCache[K, V]) remove(b, s int) {
+	c.slots[s] = ringSlot[K, V]{}
+	c.free = append(c.free, int32(s))
+
+	mask := len(c.table) - 1
+	for i, j := b, b; ; {
+		j = (j + 1) & mask
+		if c.table[j] == 0 {
+			c.table[i] = 0
+			return
+		}
+		// The entry in bucket j can be moved to bucket i if its home bucket
+		// is not cyclically within (i, j].
+		k := int(c.slots[c.table[j]-1].hash) & mask
+		if (i <= j && (k <= i || k > j)) || (i > j && k <= i && k > j) {
+			c.table[i] = c.table[j]
+			i = j
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package cache
+
+import (
+	"github.com/segmentio/datastructures/v2/container/list"
+	"github.com/segmentio/datastructures/v2/container/tree"
+)
+
+// SequencedLRU is a LRU cache which associates a sequence number with each
+// entry, and supports dropping all entries below a watermark sequence
+// regardless of how recently they were used.
+//
+// The sequences are ordered by the comparison function passed to Init or
+// NewSequencedLRU, and are indexed by an ordered map alongside the recency
+// queue. This makes the cache suitable for log-replay workloads, where entries
+// tagged with old sequences become permanently irrelevant.
+type SequencedLRU[K comparable, S any, V any] struct {
+	index map[K]*list.Element[seqEntry[K, S, V]]
+	queue list.List[seqEntry[K, S, V]]
+	seqs  tree.Map[seqKey[S], *list.Element[seqEntry[K, S, V]]]
+	next  uint64
+}
+
+type seqKey[S any] struct {
+	seq S
+	id  uint64
+}
+
+type seqEntry[K comparable, S any, V any] struct {
+	entry[K, V]
+	seq seqKey[S]
+}
+
+// NewSequencedLRU constructs a new cache using the comparison function passed
+// as argument to order the sequences of entries.
+func NewSequencedLRU[K comparable, S any, V any](cmp func(S, S) int) *SequencedLRU[K, S, V] {
+	c := new(SequencedLRU[K, S, V])
+	c.Init(cmp)
+	return c
+}
+
+// Init initializes (or re-initializes) the cache with the given comparison
+// function to order the sequences of entries.
+func (c *SequencedLRU[K, S, V]) Init(cmp func(S, S) int) {
+	c.index = make(map[K]*list.Element[seqEntry[K, S, V]])
+	c.queue.Init()
+	c.seqs.Init(func(k1, k2 seqKey[S]) int {
+		if cmp := cmp(k1.seq, k2.seq); cmp != 0 {
+			return cmp
+		}
+		switch {
+		case k1.id < k2.id:
+			return -1
+		case k1.id > k2.id:
+			return +1
+		default:
+			return 0
+		}
+	})
+	c.next = 0
+}
+
+func (c *SequencedLRU[K, S, V]) Len() int {
+	return c.queue.Len()
+}
+
+// Insert inserts an entry tagged with the given sequence in the cache, or
+// replaces the value and sequence of an existing entry.
+//
+// The cache must have been initialized by a call to NewSequencedLRU or Init or
+// the call to Insert will panic.
+func (c *SequencedLRU[K, S, V]) Insert(key K, seq S, value V) (previous V, replaced bool) {
+	e, ok := c.index[key]
+	if ok {
+		previous, replaced = e.Value.value, true
+		e.Value.value = value
+		c.seqs.Delete(e.Value.seq)
+		c.queue.MoveToFront(e)
+	} else {
+		e = c.queue.PushFront(seqEntry[K, S, V]{entry: entry[K, V]{key: key, value: value}})
+		c.index[key] = e
+	}
+	// Identifiers start at one so seqKey{seq: s} is less than all the keys
+	// with sequence s, see TrimBelowSeq.
+	c.next++
+	e.Value.seq = seqKey[S]{seq: seq, id: c.next}
+	c.seqs.Insert(e.Value.seq, e)
+	return previous, replaced
+}
+
+// Sequence returns the sequence of the entry associated with key.
+func (c *SequencedLRU[K, S, V]) Sequence(key K) (seq S, found bool) {
+	e, ok := c.index[key]
+	if ok {
+		seq, found = e.Value.seq.seq, true
+	}
+	return seq, found
+}
+
+func (c *SequencedLRU[K, S, V]) Lookup(key K) (value V, found bool) {
+	e, ok := c.index[key]
+	if ok {
+		c.queue.MoveToFront(e)
+		value, found = e.Value.value, true
+	}
+	return value, found
+}
+
+func (c *SequencedLRU[K, S, V]) Delete(key K) (value V, deleted bool) {
+	e, ok := c.index[key]
+	if ok {
+		c.remove(e)
+		value, deleted = e.Value.value, true
+	}
+	return value, deleted
+}
+
+// Evict removes the least recently used entry from the cache.
+func (c *SequencedLRU[K, S, V]) Evict() (key K, value V, evicted bool) {
+	if e := c.queue.Back(); e != nil {
+		c.remove(e)
+		key, value, evicted = e.Value.key, e.Value.value, true
+	}
+	return key, value, evicted
+}
+
+// TrimBelowSeq removes all entries with a sequence less than seq from the
+// cache, and returns the number of entries removed.
+//
+// Complexity: O(log n) + O(k log n) with k being the number of entries
+// removed, or O(n) when k is greater than n/2
+func (c *SequencedLRU[K, S, V]) TrimBelowSeq(seq S) int {
+	min, _, ok := c.seqs.Min()
+	if !ok {
+		return 0
+	}
+	entries := c.seqs.PopRange(min, seqKey[S]{seq: seq})
+	for _, e := range entries {
+		delete(c.index, e.Value.Value.key)
+		c.queue.Remove(e.Value)
+	}
+	return len(entries)
+}
+
+func (c *SequencedLRU[K, S, V]) Range(f func(K, V) bool) {
+	for _, e := range c.index {
+		if !f(e.Value.key, e.Value.value) {
+			break
+		}
+	}
+}
+
+func (c *SequencedLRU[K, S, V]) remove(e *list.Element[seqEntry[K, S, V]]) {
+	delete(c.index, e.Value.key)
+	c.queue.Remove(e)
+	c.seqs.Delete(e.Value.seq)
+}
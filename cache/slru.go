@@ -0,0 +1,145 @@
+package cache
+
+import (
+	"unsafe"
+
+	"github.com/segmentio/datastructures/v2/container/list"
+)
+
+// SLRU is an Interface implementation of a segmented LRU cache.
+//
+// Entries are first inserted in a probationary segment, and are promoted to a
+// protected segment when they are accessed again. Evictions select the least
+// recently used entries of the probationary segment first, which makes the
+// cache resistant to scans: entries accessed only once never displace entries
+// that were accessed multiple times.
+//
+// The protected segment is limited to 80% of the cache entries when evicting;
+// if it has grown beyond this limit, its least recently used entries are
+// demoted back to the probationary segment before selecting an entry to evict.
+// Since evictions usually happen when the cache is full, this allows the cache
+// to warm up without demoting entries too early.
+type SLRU[K comparable, V any] struct {
+	index     map[K]*list.Element[slruEntry[K, V]]
+	probation list.List[slruEntry[K, V]]
+	protected list.List[slruEntry[K, V]]
+}
+
+type slruEntry[K comparable, V any] struct {
+	entry[K, V]
+	protected bool
+}
+
+func (slru *SLRU[K, V]) Len() int {
+	return len(slru.index)
+}
+
+func (slru *SLRU[K, V]) Insert(key K, value V) (previous V, replaced bool) {
+	if slru.index == nil {
+		slru.index = make(map[K]*list.Element[slruEntry[K, V]])
+	}
+	e, ok := slru.index[key]
+	if ok {
+		previous, replaced = e.Value.value, true
+		e.Value.value = value
+		slru.promote(e)
+	} else {
+		slru.index[key] = slru.probation.PushFront(slruEntry[K, V]{
+			entry: entry[K, V]{key: key, value: value},
+		})
+	}
+	return previous, replaced
+}
+
+func (slru *SLRU[K, V]) Lookup(key K) (value V, found bool) {
+	e, ok := slru.index[key]
+	if ok {
+		slru.promote(e)
+		value, found = e.Value.value, true
+	}
+	return value, found
+}
+
+// Peek returns the value associated with key without promoting the entry.
+func (slru *SLRU[K, V]) Peek(key K) (value V, found bool) {
+	e, ok := slru.index[key]
+	if ok {
+		value, found = e.Value.value, true
+	}
+	return value, found
+}
+
+func (slru *SLRU[K, V]) LookupOrInsert(key K, value V) (actual V, loaded bool) {
+	if e, ok := slru.index[key]; ok {
+		slru.promote(e)
+		return e.Value.value, true
+	}
+	slru.Insert(key, value)
+	return value, false
+}
+
+func (slru *SLRU[K, V]) Delete(key K) (value V, deleted bool) {
+	e, ok := slru.index[key]
+	if ok {
+		delete(slru.index, key)
+		slru.segmentOf(e).Remove(e)
+		value, deleted = e.Value.value, true
+	}
+	return value, deleted
+}
+
+func (slru *SLRU[K, V]) Evict() (key K, value V, evicted bool) {
+	for 5*slru.protected.Len() > 4*len(slru.index) {
+		d := slru.protected.Back()
+		slru.protected.Remove(d)
+		d.Value.protected = false
+		slru.probation.PushFrontElement(d)
+	}
+
+	e := slru.probation.Back()
+	if e == nil {
+		e = slru.protected.Back()
+	}
+	if e != nil {
+		slru.segmentOf(e).Remove(e)
+		delete(slru.index, e.Value.key)
+		key, value, evicted = e.Value.key, e.Value.value, true
+	}
+	return key, value, evicted
+}
+
+func (slru *SLRU[K, V]) Range(f func(K, V) bool) {
+	for _, e := range slru.index {
+		if !f(e.Value.key, e.Value.value) {
+			break
+		}
+	}
+}
+
+// MemoryUsage returns an estimate of the memory footprint of the cache, in
+// bytes. See LRU.MemoryUsage for details.
+func (slru *SLRU[K, V]) MemoryUsage() int64 {
+	var key K
+	var elem list.Element[slruEntry[K, V]]
+	size := int64(unsafe.Sizeof(*slru))
+	size += int64(len(slru.index)) * int64(unsafe.Sizeof(elem)+unsafe.Sizeof(key)+unsafe.Sizeof(&elem))
+	return size
+}
+
+func (slru *SLRU[K, V]) segmentOf(e *list.Element[slruEntry[K, V]]) *list.List[slruEntry[K, V]] {
+	if e.Value.protected {
+		return &slru.protected
+	}
+	return &slru.probation
+}
+
+func (slru *SLRU[K, V]) promote(e *list.Element[slruEntry[K, V]]) {
+	if e.Value.protected {
+		slru.protected.MoveToFront(e)
+		return
+	}
+
+	slru.probation.Remove(e)
+	e.Value.protected = true
+	slru.protected.PushFrontElement(e)
+}
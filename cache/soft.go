@@ -0,0 +1,105 @@
+package cache
+
+// SoftLRU is an Interface implementation of a LRU cache with a second "soft"
+// tier retaining recently evicted entries.
+//
+// Evicted entries are demoted to the soft tier instead of being discarded, and
+// are only dropped when the soft tier exceeds its capacity, in least recently
+// used order. Looking up a key held in the soft tier moves its entry back to
+// the main tier, so a brief spike of insertions past the capacity of the cache
+// does not immediately lose all the entries it displaced.
+//
+// Entries of the soft tier are not accounted for by Len or presented by Range.
+// Since evicted values may be returned again by Lookup, SoftLRU is suited to
+// memoize derived values, but not to hold resources that must be released when
+// evicted.
+//
+// The zero-value has a soft capacity of zero, in which case it behaves like a
+// LRU.
+type SoftLRU[K comparable, V any] struct {
+	hard LRU[K, V]
+	soft LRU[K, V]
+	size int
+}
+
+// SetSoftCapacity sets the maximum number of entries retained in the soft
+// tier. If the soft tier holds more than n entries, the least recently used
+// ones are dropped.
+func (c *SoftLRU[K, V]) SetSoftCapacity(n int) {
+	if n < 0 {
+		n = 0
+	}
+	c.size = n
+	c.shrink()
+}
+
+// SoftLen returns the number of entries held in the soft tier of the cache.
+func (c *SoftLRU[K, V]) SoftLen() int {
+	return c.soft.Len()
+}
+
+func (c *SoftLRU[K, V]) Len() int {
+	return c.hard.Len()
+}
+
+func (c *SoftLRU[K, V]) Insert(key K, value V) (previous V, replaced bool) {
+	if previous, replaced = c.soft.Delete(key); replaced {
+		c.hard.Insert(key, value)
+		return previous, replaced
+	}
+	return c.hard.Insert(key, value)
+}
+
+func (c *SoftLRU[K, V]) Lookup(key K) (value V, found bool) {
+	if value, found = c.hard.Lookup(key); !found {
+		if value, found = c.soft.Delete(key); found {
+			c.hard.Insert(key, value)
+		}
+	}
+	return value, found
+}
+
+// Peek returns the value associated with key without affecting the eviction
+// order of entries, or moving entries of the soft tier back to the main tier.
+func (c *SoftLRU[K, V]) Peek(key K) (value V, found bool) {
+	if value, found = c.hard.Peek(key); !found {
+		value, found = c.soft.Peek(key)
+	}
+	return value, found
+}
+
+func (c *SoftLRU[K, V]) LookupOrInsert(key K, value V) (actual V, loaded bool) {
+	if actual, loaded = c.Lookup(key); loaded {
+		return actual, loaded
+	}
+	c.hard.Insert(key, value)
+	return value, false
+}
+
+func (c *SoftLRU[K, V]) Delete(key K) (value V, deleted bool) {
+	if value, deleted = c.hard.Delete(key); !deleted {
+		value, deleted = c.soft.Delete(key)
+	}
+	return value, deleted
+}
+
+// Evict removes the least recently used entry from the main tier of the cache
+// and demotes it to the soft tier, dropping the least recently used entries of
+// the soft tier if it exceeds its capacity.
+func (c *SoftLRU[K, V]) Evict() (key K, value V, evicted bool) {
+	if key, value, evicted = c.hard.Evict(); evicted && c.size > 0 {
+		c.soft.Insert(key, value)
+		c.shrink()
+	}
+	return key, value, evicted
+}
+
+func (c *SoftLRU[K, V]) Range(f func(K, V) bool) {
+	c.hard.Range(f)
+}
+
+func (c *SoftLRU[K, V]) shrink() {
+	for c.soft.Len() > c.size {
+		c.soft.Evict()
+	}
+}
@@ -1,5 +1,7 @@
 package compare
 
+import "sort"
+
 // Ordered is a type constraint enumerating primitive types that support the
 // "<" and ">" operators.
 type Ordered interface {
@@ -17,3 +19,108 @@ func Function[T Ordered](a, b T) int {
 		return 0
 	}
 }
+
+// Float is a comparison function for floating point types which defines a
+// total order over all values, including NaN.
+//
+// Function does not order NaN values since all comparisons with NaN are false,
+// which reports NaN as equal to every value and breaks the invariants of
+// ordered data structures. Float orders NaN values after all other values,
+// including positive infinity, and reports NaN values as equal to each other.
+// Negative and positive zeros are equal, like with Function.
+func Float[T ~float32 | ~float64](a, b T) int {
+	switch aNaN, bNaN := a != a, b != b; {
+	case aNaN && bNaN:
+		return 0
+	case aNaN:
+		return +1
+	case bNaN:
+		return -1
+	}
+	return Function(a, b)
+}
+
+// Min returns the smallest of a and b.
+func Min[T Ordered](a, b T) T {
+	if b < a {
+		return b
+	}
+	return a
+}
+
+// Max returns the largest of a and b.
+func Max[T Ordered](a, b T) T {
+	if b > a {
+		return b
+	}
+	return a
+}
+
+// Clamp returns x limited to the closed interval [lo, hi]. If lo is greater
+// than hi, the interval is empty and lo is returned.
+func Clamp[T Ordered](x, lo, hi T) T {
+	switch {
+	case lo > hi || x < lo:
+		return lo
+	case x > hi:
+		return hi
+	default:
+		return x
+	}
+}
+
+// Sort sorts the slice s in the order defined by the comparison function cmp.
+// The sort is not guaranteed to be stable.
+func Sort[T any](s []T, cmp func(T, T) int) {
+	sort.Slice(s, func(i, j int) bool { return cmp(s[i], s[j]) < 0 })
+}
+
+// SortStable is like Sort but retains the original order of elements which
+// compare equal.
+func SortStable[T any](s []T, cmp func(T, T) int) {
+	sort.SliceStable(s, func(i, j int) bool { return cmp(s[i], s[j]) < 0 })
+}
+
+// IsSorted returns true if the slice s is sorted in the order defined by the
+// comparison function cmp.
+func IsSorted[T any](s []T, cmp func(T, T) int) bool {
+	for i := 1; i < len(s); i++ {
+		if cmp(s[i-1], s[i]) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Counter wraps a comparison function, counting the number of times it is
+// called. It is intended to profile data structures using expensive comparison
+// functions, for example:
+//
+//	c := compare.Counting(strings.Compare)
+//	m := tree.NewMap[string, int](c.Compare)
+//	...
+//	fmt.Println(c.Count())
+//
+// Counter values are not safe to use concurrently from multiple goroutines.
+type Counter[T any] struct {
+	cmp   func(T, T) int
+	count uint64
+}
+
+// Counting returns a Counter wrapping the comparison function cmp.
+func Counting[T any](cmp func(T, T) int) *Counter[T] {
+	return &Counter[T]{cmp: cmp}
+}
+
+// Compare calls the wrapped comparison function and increments the count.
+func (c *Counter[T]) Compare(a, b T) int {
+	c.count++
+	return c.cmp(a, b)
+}
+
+// Count returns the number of calls to Compare since the counter was created or
+// last reset.
+func (c *Counter[T]) Count() uint64 { return c.count }
+
+// Reset sets the count back to zero.
+func (c *Counter[T]) Reset() { c.count = 0 }
@@ -0,0 +1,122 @@
+package compare
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMinMax(t *testing.T) {
+	if m := Min(1, 2); m != 1 {
+		t.Errorf("Min(1, 2) = %d, want 1", m)
+	}
+	if m := Min("b", "a"); m != "a" {
+		t.Errorf("Min(b, a) = %s, want a", m)
+	}
+	if m := Max(1, 2); m != 2 {
+		t.Errorf("Max(1, 2) = %d, want 2", m)
+	}
+	if m := Max("b", "a"); m != "b" {
+		t.Errorf("Max(b, a) = %s, want b", m)
+	}
+}
+
+func TestFloat(t *testing.T) {
+	nan := math.NaN()
+	inf := math.Inf(+1)
+
+	tests := []struct {
+		a, b float64
+		want int
+	}{
+		{a: 1, b: 2, want: -1},
+		{a: 2, b: 1, want: +1},
+		{a: 1, b: 1, want: 0},
+		{a: math.Copysign(0, -1), b: 0, want: 0},
+		{a: nan, b: nan, want: 0},
+		{a: nan, b: inf, want: +1},
+		{a: inf, b: nan, want: -1},
+		{a: -inf, b: nan, want: -1},
+	}
+
+	for _, test := range tests {
+		if got := Float(test.a, test.b); got != test.want {
+			t.Errorf("Float(%g, %g) = %d, want %d", test.a, test.b, got, test.want)
+		}
+	}
+
+	s := []float32{float32(nan), 3, float32(-inf), float32(nan), 1}
+	Sort(s, Float[float32])
+	if !IsSorted(s, Float[float32]) || s[0] != float32(-inf) || s[2] != 3 || s[3] == s[3] || s[4] == s[4] {
+		t.Errorf("wrong order of sorted floats: %v", s)
+	}
+}
+
+func TestClamp(t *testing.T) {
+	tests := []struct {
+		x, lo, hi int
+		want      int
+	}{
+		{x: 5, lo: 0, hi: 10, want: 5},
+		{x: -1, lo: 0, hi: 10, want: 0},
+		{x: 11, lo: 0, hi: 10, want: 10},
+		{x: 0, lo: 0, hi: 0, want: 0},
+		{x: 5, lo: 10, hi: 0, want: 10},
+	}
+
+	for _, test := range tests {
+		if got := Clamp(test.x, test.lo, test.hi); got != test.want {
+			t.Errorf("Clamp(%d, %d, %d) = %d, want %d", test.x, test.lo, test.hi, got, test.want)
+		}
+	}
+}
+
+func TestSort(t *testing.T) {
+	s := []int{5, 2, 4, 1, 3}
+	if IsSorted(s, Function[int]) {
+		t.Error("unsorted slice reported as sorted")
+	}
+
+	Sort(s, Function[int])
+	if !IsSorted(s, Function[int]) {
+		t.Errorf("slice not sorted: %v", s)
+	}
+	for i, v := range s {
+		if v != i+1 {
+			t.Errorf("wrong value at index %d: got=%d want=%d", i, v, i+1)
+		}
+	}
+}
+
+func TestSortStable(t *testing.T) {
+	type pair struct{ k, v int }
+	s := []pair{{2, 0}, {1, 1}, {2, 2}, {1, 3}, {0, 4}}
+	SortStable(s, func(a, b pair) int { return Function(a.k, b.k) })
+
+	want := []pair{{0, 4}, {1, 1}, {1, 3}, {2, 0}, {2, 2}}
+	for i := range s {
+		if s[i] != want[i] {
+			t.Errorf("wrong value at index %d: got=%v want=%v", i, s[i], want[i])
+		}
+	}
+}
+
+func TestCounter(t *testing.T) {
+	c := Counting(Function[int])
+	s := []int{5, 2, 4, 1, 3}
+
+	if !IsSorted([]int{1, 2, 3}, c.Compare) {
+		t.Error("sorted slice reported as unsorted")
+	}
+	if n := c.Count(); n != 2 {
+		t.Errorf("wrong number of comparisons: got=%d want=2", n)
+	}
+
+	c.Reset()
+	Sort(s, c.Compare)
+	if !IsSorted(s, Function[int]) {
+		t.Errorf("slice not sorted: %v", s)
+	}
+	if n := c.Count(); n == 0 || n > 10 {
+		t.Errorf("wrong number of comparisons sorting 5 elements: got=%d", n)
+	}
+}
@@ -0,0 +1,146 @@
+// Package bitset provides the implementation of a set of non-negative integers
+// represented as a bit vector.
+package bitset
+
+import "math/bits"
+
+// BitSet is a set of non-negative integers, using one bit of memory per integer
+// up to the largest integer of the set.
+//
+// Bit sets are more compact and faster than ordered sets like tree.Tree when
+// the integers are dense, for example to track membership of small identifiers
+// or offsets.
+//
+// The zero-value is a valid, empty set. The set grows as needed when integers
+// are added to it. Methods taking integers as arguments panic if they are
+// negative, except NextSet.
+type BitSet struct {
+	words []uint64
+}
+
+// New constructs a bit set with capacity for integers in the range [0, n)
+// before needing to grow.
+func New(n int) *BitSet {
+	return &BitSet{words: make([]uint64, 0, (n+63)/64)}
+}
+
+// Set adds i to the set.
+//
+// Complexity: O(1) amortized
+func (s *BitSet) Set(i int) {
+	w := index(i)
+	if w >= len(s.words) {
+		s.grow(w + 1)
+	}
+	s.words[w] |= 1 << (uint(i) % 64)
+}
+
+// Clear removes i from the set.
+//
+// Complexity: O(1)
+func (s *BitSet) Clear(i int) {
+	if w := index(i); w < len(s.words) {
+		s.words[w] &^= 1 << (uint(i) % 64)
+	}
+}
+
+// Test returns true if i is in the set.
+//
+// Complexity: O(1)
+func (s *BitSet) Test(i int) bool {
+	w := index(i)
+	return w < len(s.words) && (s.words[w]&(1<<(uint(i)%64))) != 0
+}
+
+// Count returns the number of integers in the set.
+//
+// Complexity: O(n) with n being the largest integer of the set
+func (s *BitSet) Count() int {
+	count := 0
+	for _, w := range s.words {
+		count += bits.OnesCount64(w)
+	}
+	return count
+}
+
+// NextSet returns the smallest integer of the set greater or equal to i. The
+// found result is false if there are no such integers.
+//
+// Iterating over the integers of the set can be done with:
+//
+//	for i, ok := s.NextSet(0); ok; i, ok = s.NextSet(i + 1) {
+//		...
+//	}
+//
+// Complexity: O(n) with n being the distance to the next integer
+func (s *BitSet) NextSet(i int) (next int, found bool) {
+	if i < 0 {
+		i = 0
+	}
+	w := i / 64
+	if w >= len(s.words) {
+		return 0, false
+	}
+	if word := s.words[w] >> (uint(i) % 64); word != 0 {
+		return i + bits.TrailingZeros64(word), true
+	}
+	for w++; w < len(s.words); w++ {
+		if word := s.words[w]; word != 0 {
+			return 64*w + bits.TrailingZeros64(word), true
+		}
+	}
+	return 0, false
+}
+
+// Union adds the integers of other to s.
+//
+// Complexity: O(n) with n being the largest integer of other
+func (s *BitSet) Union(other *BitSet) {
+	if len(other.words) > len(s.words) {
+		s.grow(len(other.words))
+	}
+	for i, w := range other.words {
+		s.words[i] |= w
+	}
+}
+
+// Intersect removes the integers of s which are not in other.
+//
+// Complexity: O(n) with n being the largest integer of s
+func (s *BitSet) Intersect(other *BitSet) {
+	for i := range s.words {
+		if i < len(other.words) {
+			s.words[i] &= other.words[i]
+		} else {
+			s.words[i] = 0
+		}
+	}
+}
+
+// Difference removes the integers of other from s.
+//
+// Complexity: O(n) with n being the largest integer of s
+func (s *BitSet) Difference(other *BitSet) {
+	for i := range s.words {
+		if i < len(other.words) {
+			s.words[i] &^= other.words[i]
+		}
+	}
+}
+
+func (s *BitSet) grow(n int) {
+	if n <= cap(s.words) {
+		s.words = s.words[:n]
+		return
+	}
+	words := make([]uint64, n, 2*n)
+	copy(words, s.words)
+	s.words = words
+}
+
+func index(i int) int {
+	if i < 0 {
+		panic("bitset: negative integer")
+	}
+	return i / 64
+}
@@ -0,0 +1,119 @@
+package bitset
+
+import (
+	"fmt"
+	"testing"
+)
+
+func collect(s *BitSet) []int {
+	values := []int{}
+	for i, ok := s.NextSet(0); ok; i, ok = s.NextSet(i + 1) {
+		values = append(values, i)
+	}
+	return values
+}
+
+func fromSlice(values ...int) *BitSet {
+	s := new(BitSet)
+	for _, v := range values {
+		s.Set(v)
+	}
+	return s
+}
+
+func TestBitSet(t *testing.T) {
+	s := New(10)
+	values := []int{0, 1, 63, 64, 65, 127, 128, 1000}
+	for _, v := range values {
+		s.Set(v)
+	}
+
+	for _, v := range values {
+		if !s.Test(v) {
+			t.Errorf("value not found in set: %d", v)
+		}
+	}
+	for _, v := range []int{2, 62, 66, 129, 999, 1001, 100000} {
+		if s.Test(v) {
+			t.Errorf("value found in set: %d", v)
+		}
+	}
+	if n := s.Count(); n != len(values) {
+		t.Errorf("wrong count: got=%d want=%d", n, len(values))
+	}
+	if got := collect(s); fmt.Sprint(got) != fmt.Sprint(values) {
+		t.Errorf("wrong values: got=%v want=%v", got, values)
+	}
+
+	s.Clear(64)
+	s.Clear(1000)
+	s.Clear(5000)
+	if got := fmt.Sprint(collect(s)); got != "[0 1 63 65 127 128]" {
+		t.Errorf("wrong values after clear: got=%s want=[0 1 63 65 127 128]", got)
+	}
+}
+
+func TestBitSetNextSet(t *testing.T) {
+	s := fromSlice(3, 64, 200)
+
+	tests := []struct {
+		from  int
+		next  int
+		found bool
+	}{
+		{from: -1, next: 3, found: true},
+		{from: 0, next: 3, found: true},
+		{from: 3, next: 3, found: true},
+		{from: 4, next: 64, found: true},
+		{from: 65, next: 200, found: true},
+		{from: 201, found: false},
+		{from: 10000, found: false},
+	}
+
+	for _, test := range tests {
+		next, found := s.NextSet(test.from)
+		if next != test.next || found != test.found {
+			t.Errorf("NextSet(%d): got=%d,%t want=%d,%t", test.from, next, found, test.next, test.found)
+		}
+	}
+
+	var empty BitSet
+	if _, found := empty.NextSet(0); found {
+		t.Error("NextSet found a value in an empty set")
+	}
+}
+
+func TestBitSetOperations(t *testing.T) {
+	tests := []struct {
+		scenario string
+		op       func(a, b *BitSet)
+		a, b     []int
+		want     string
+	}{
+		{scenario: "union", op: (*BitSet).Union, a: []int{1, 2}, b: []int{2, 300}, want: "[1 2 300]"},
+		{scenario: "union with empty", op: (*BitSet).Union, a: []int{1}, b: nil, want: "[1]"},
+		{scenario: "intersect", op: (*BitSet).Intersect, a: []int{1, 2, 300}, b: []int{2, 3}, want: "[2]"},
+		{scenario: "intersect with empty", op: (*BitSet).Intersect, a: []int{1, 2}, b: nil, want: "[]"},
+		{scenario: "difference", op: (*BitSet).Difference, a: []int{1, 2, 300}, b: []int{2, 300, 400}, want: "[1]"},
+		{scenario: "difference with empty", op: (*BitSet).Difference, a: []int{1}, b: nil, want: "[1]"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			a := fromSlice(test.a...)
+			test.op(a, fromSlice(test.b...))
+			if got := fmt.Sprint(collect(a)); got != test.want {
+				t.Errorf("wrong values: got=%s want=%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestBitSetNegative(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("setting a negative integer did not panic")
+		}
+	}()
+	new(BitSet).Set(-1)
+}
@@ -0,0 +1,85 @@
+package list
+
+// Cursor is a position in a list, supporting iteration and removal of elements
+// during the iteration.
+//
+// Unlike holding an *Element and calling Next after removing it from the list
+// (which returns nil since the element was unlinked), removing the element at
+// the position of a cursor advances the cursor to the next element.
+//
+// A cursor becomes invalid when it moves past either end of the list, or if
+// its current element is removed from the list by other means than the cursor.
+type Cursor[T any] struct {
+	list *List[T]
+	elem *Element[T]
+}
+
+// FrontCursor returns a cursor positioned on the first element of list l.
+// The cursor is invalid if the list is empty.
+func (l *List[T]) FrontCursor() Cursor[T] {
+	return Cursor[T]{list: l, elem: l.Front()}
+}
+
+// BackCursor returns a cursor positioned on the last element of list l.
+// The cursor is invalid if the list is empty.
+func (l *List[T]) BackCursor() Cursor[T] {
+	return Cursor[T]{list: l, elem: l.Back()}
+}
+
+// Valid returns true if the cursor is positioned on an element of its list.
+func (c *Cursor[T]) Valid() bool {
+	return c.elem != nil && c.elem.list == c.list
+}
+
+// Element returns the element at the position of the cursor, or nil if the
+// cursor is invalid.
+func (c *Cursor[T]) Element() *Element[T] {
+	if !c.Valid() {
+		return nil
+	}
+	return c.elem
+}
+
+// Value returns the value of the element at the position of the cursor, or the
+// zero-value if the cursor is invalid.
+func (c *Cursor[T]) Value() (value T) {
+	if c.Valid() {
+		value = c.elem.Value
+	}
+	return value
+}
+
+// Next moves the cursor to the next element of the list and reports whether
+// the cursor is still valid.
+func (c *Cursor[T]) Next() bool {
+	if c.Valid() {
+		c.elem = c.elem.Next()
+	} else {
+		c.elem = nil
+	}
+	return c.elem != nil
+}
+
+// Prev moves the cursor to the previous element of the list and reports
+// whether the cursor is still valid.
+func (c *Cursor[T]) Prev() bool {
+	if c.Valid() {
+		c.elem = c.elem.Prev()
+	} else {
+		c.elem = nil
+	}
+	return c.elem != nil
+}
+
+// Remove removes the element at the position of the cursor from the list and
+// moves the cursor to the next element. It returns the value of the removed
+// element, or the zero-value if the cursor was invalid.
+func (c *Cursor[T]) Remove() (value T) {
+	if !c.Valid() {
+		c.elem = nil
+		return value
+	}
+	e := c.elem
+	c.elem = e.Next()
+	return c.list.Remove(e)
+}
@@ -0,0 +1,90 @@
+package list
+
+import "testing"
+
+func TestCursor(t *testing.T) {
+	l := New[int]()
+	for i := 1; i <= 5; i++ {
+		l.PushBack(i)
+	}
+
+	sum := 0
+	for c := l.FrontCursor(); c.Valid(); c.Next() {
+		sum += c.Value()
+	}
+	if sum != 15 {
+		t.Errorf("wrong sum of values iterating forward: got=%d want=15", sum)
+	}
+
+	values := []int{}
+	for c := l.BackCursor(); c.Valid(); c.Prev() {
+		values = append(values, c.Value())
+	}
+	if len(values) != 5 || values[0] != 5 || values[4] != 1 {
+		t.Errorf("wrong values iterating backward: %v", values)
+	}
+}
+
+func TestCursorRemove(t *testing.T) {
+	l := New[int]()
+	for i := 1; i <= 6; i++ {
+		l.PushBack(i)
+	}
+
+	// Remove even values while iterating.
+	for c := l.FrontCursor(); c.Valid(); {
+		if c.Value()%2 == 0 {
+			c.Remove()
+		} else {
+			c.Next()
+		}
+	}
+	checkList(t, l, 1, 3, 5)
+
+	// Remove all remaining values, the cursor becomes invalid after the last.
+	c := l.FrontCursor()
+	for i := 0; i < 3; i++ {
+		if v := c.Remove(); v != 2*i+1 {
+			t.Errorf("wrong value removed: got=%d want=%d", v, 2*i+1)
+		}
+	}
+	if c.Valid() {
+		t.Error("cursor still valid after removing the last element")
+	}
+	if v := c.Remove(); v != 0 {
+		t.Errorf("wrong value removed from invalid cursor: got=%d want=0", v)
+	}
+	checkListLen(t, l, 0)
+}
+
+func TestCursorElementRemovedElsewhere(t *testing.T) {
+	l := New[int]()
+	e := l.PushBack(1)
+	l.PushBack(2)
+
+	c := l.FrontCursor()
+	l.Remove(e)
+
+	if c.Valid() {
+		t.Error("cursor still valid after its element was removed from the list")
+	}
+	if c.Element() != nil {
+		t.Error("invalid cursor returned a non-nil element")
+	}
+	if c.Next() {
+		t.Error("invalid cursor moved to a valid position")
+	}
+	checkList(t, l, 2)
+}
+
+func TestCursorEmptyList(t *testing.T) {
+	var l List[int]
+	c := l.FrontCursor()
+	if c.Valid() {
+		t.Error("cursor of empty list is valid")
+	}
+	c = l.BackCursor()
+	if c.Prev() {
+		t.Error("cursor of empty list moved to a valid position")
+	}
+}
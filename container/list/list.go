@@ -15,7 +15,10 @@ type Element[T any] struct {
 	// element (l.Front()).
 	next, prev *Element[T]
 
-	// The list to which this element belongs.
+	// The list to which this element belongs, set on insertion and cleared
+	// on removal. Operations of a list ignore elements which do not belong to
+	// it, which guarantees that lists are never corrupted by elements moved
+	// across lists.
 	list *List[T]
 
 	// The value stored with this element.
@@ -137,6 +140,17 @@ func (l *List[T]) Remove(e *Element[T]) T {
 	return e.Value
 }
 
+// RemoveChecked is like Remove but returns a boolean indicating whether e was
+// an element of l and has been removed.
+// The element must not be nil.
+func (l *List[T]) RemoveChecked(e *Element[T]) bool {
+	if e.list != l {
+		return false
+	}
+	l.remove(e)
+	return true
+}
+
 // PushFront inserts a new element e with value v at the front of list l and returns e.
 func (l *List[T]) PushFront(v T) *Element[T] {
 	l.lazyInit()
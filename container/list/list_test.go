@@ -212,6 +212,32 @@ func TestRemove(t *testing.T) {
 	checkListPointers(t, l, []*Element[int]{e2})
 }
 
+func TestRemoveChecked(t *testing.T) {
+	l1 := New[int]()
+	e1 := l1.PushBack(1)
+	l2 := New[int]()
+	e2 := l2.PushBack(2)
+
+	if l1.RemoveChecked(e2) {
+		t.Error("l1.RemoveChecked(e2) = true, want false")
+	}
+	checkListPointers(t, l1, []*Element[int]{e1})
+	checkListPointers(t, l2, []*Element[int]{e2})
+
+	if !l1.RemoveChecked(e1) {
+		t.Error("l1.RemoveChecked(e1) = false, want true")
+	}
+	checkListPointers(t, l1, []*Element[int]{})
+
+	if l1.RemoveChecked(e1) {
+		t.Error("l1.RemoveChecked(e1) = true after removal, want false")
+	}
+	if l1.RemoveChecked(new(Element[int])) {
+		t.Error("l1.RemoveChecked(zero element) = true, want false")
+	}
+	checkListPointers(t, l1, []*Element[int]{})
+}
+
 func TestIssue4103(t *testing.T) {
 	l1 := New[int]()
 	l1.PushBack(1)
@@ -340,3 +366,23 @@ func TestMoveUnknownMark(t *testing.T) {
 	checkList(t, &l1, 1)
 	checkList(t, &l2, 2)
 }
+
+// Test that lists are not modified when calling Remove, MoveToFront, or
+// MoveToBack with an element of another list.
+func TestForeignElement(t *testing.T) {
+	var l1 List[int]
+	e1 := l1.PushBack(1)
+	l1.PushBack(2)
+
+	var l2 List[int]
+	e3 := l2.PushBack(3)
+	l2.PushBack(4)
+
+	l1.MoveToFront(e3)
+	l1.MoveToBack(e3)
+	l2.MoveToFront(e1)
+	l2.MoveToBack(e1)
+	l1.Remove(e3)
+	checkList(t, &l1, 1, 2)
+	checkList(t, &l2, 3, 4)
+}
@@ -0,0 +1,53 @@
+package queue
+
+import "sync"
+
+// Dispatcher is a bounded work queue served by a pool of worker goroutines,
+// each calling a handler function for the items it pops from the queue.
+//
+// Dispatcher values must be constructed by a call to NewDispatcher.
+type Dispatcher[T any] struct {
+	queue   *Blocking[T]
+	workers sync.WaitGroup
+}
+
+// NewDispatcher starts a dispatcher calling handle for each item submitted to
+// it, from the given number of worker goroutines. Up to capacity items may be
+// waiting to be handled before calls to Submit block.
+//
+// The method panics if capacity or workers are not positive.
+func NewDispatcher[T any](capacity, workers int, handle func(T)) *Dispatcher[T] {
+	if workers <= 0 {
+		panic("queue: the number of workers of a dispatcher must be positive")
+	}
+	d := &Dispatcher[T]{queue: NewBlocking[T](capacity)}
+	d.workers.Add(workers)
+	for i := 0; i < workers; i++ {
+		go d.run(handle)
+	}
+	return d
+}
+
+func (d *Dispatcher[T]) run(handle func(T)) {
+	defer d.workers.Done()
+	for {
+		item, ok := d.queue.Pop()
+		if !ok {
+			return
+		}
+		handle(item)
+	}
+}
+
+// Submit queues an item to be handled by one of the workers, waiting for space
+// in the queue if it is full. The method returns ErrClosed if the dispatcher
+// was closed, in which case the item is not handled.
+func (d *Dispatcher[T]) Submit(item T) error { return d.queue.Push(item) }
+
+// Close stops accepting new items, and waits for the workers to handle the
+// items remaining in the queue before returning. Calling Close more than once
+// waits for the workers again.
+func (d *Dispatcher[T]) Close() {
+	d.queue.Close()
+	d.workers.Wait()
+}
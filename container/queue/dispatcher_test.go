@@ -0,0 +1,87 @@
+package queue
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDispatcher(t *testing.T) {
+	const N = 1000
+	const workers = 4
+
+	var active, maxActive int32
+	handled := make([]int32, N)
+
+	d := NewDispatcher[int](8, workers, func(item int) {
+		n := atomic.AddInt32(&active, 1)
+		for {
+			peak := atomic.LoadInt32(&maxActive)
+			if n <= peak || atomic.CompareAndSwapInt32(&maxActive, peak, n) {
+				break
+			}
+		}
+		atomic.AddInt32(&handled[item], 1)
+		atomic.AddInt32(&active, -1)
+	})
+
+	for i := 0; i < N; i++ {
+		if err := d.Submit(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d.Close()
+
+	for i, n := range handled {
+		if n != 1 {
+			t.Errorf("item %d handled %d times", i, n)
+		}
+	}
+	if peak := atomic.LoadInt32(&maxActive); peak > workers {
+		t.Errorf("too many concurrent calls to the handler: got=%d want<=%d", peak, workers)
+	}
+	if err := d.Submit(N); err != ErrClosed {
+		t.Errorf("wrong error submitting to a closed dispatcher: got=%v want=%v", err, ErrClosed)
+	}
+}
+
+func TestDispatcherCloseDrains(t *testing.T) {
+	release := make(chan struct{})
+	mutex := sync.Mutex{}
+	handled := []int{}
+
+	d := NewDispatcher[int](10, 1, func(item int) {
+		<-release
+		mutex.Lock()
+		handled = append(handled, item)
+		mutex.Unlock()
+	})
+	for i := 0; i < 10; i++ {
+		d.Submit(i)
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		d.Close()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+		t.Fatal("close returned before the queued items were handled")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(release)
+	<-closed
+
+	if len(handled) != 10 {
+		t.Fatalf("wrong number of items handled: got=%d want=10", len(handled))
+	}
+	for i, item := range handled {
+		if item != i {
+			t.Errorf("items handled out of order: got=%d at index %d", item, i)
+		}
+	}
+}
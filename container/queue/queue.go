@@ -0,0 +1,97 @@
+// Package queue contains the implementation of a bounded blocking queue, and
+// of a dispatcher fanning the items of a queue out to a pool of goroutines.
+package queue
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/segmentio/datastructures/v2/container/list"
+)
+
+// ErrClosed is returned when pushing items to a queue which was closed.
+var ErrClosed = errors.New("queue: closed")
+
+// Blocking is a bounded first-in first-out queue safe to use concurrently from
+// multiple goroutines. Pushing items blocks while the queue is full, and
+// popping items blocks while the queue is empty.
+//
+// Blocking values must be constructed by a call to NewBlocking.
+type Blocking[T any] struct {
+	mutex    sync.Mutex
+	notEmpty sync.Cond
+	notFull  sync.Cond
+	items    list.List[T]
+	capacity int
+	closed   bool
+}
+
+// NewBlocking constructs a queue holding up to capacity items. The method
+// panics if the capacity is not positive.
+func NewBlocking[T any](capacity int) *Blocking[T] {
+	if capacity <= 0 {
+		panic("queue: the capacity of a blocking queue must be positive")
+	}
+	q := &Blocking[T]{capacity: capacity}
+	q.notEmpty.L = &q.mutex
+	q.notFull.L = &q.mutex
+	return q
+}
+
+// Len returns the number of items currently held in the queue.
+//
+// Complexity: O(1)
+func (q *Blocking[T]) Len() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.items.Len()
+}
+
+// Push adds an item at the back of the queue, waiting for space to become
+// available if the queue is full. The method returns ErrClosed if the queue
+// was closed, in which case the item is not added.
+//
+// Complexity: O(1)
+func (q *Blocking[T]) Push(item T) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	for !q.closed && q.items.Len() == q.capacity {
+		q.notFull.Wait()
+	}
+	if q.closed {
+		return ErrClosed
+	}
+	q.items.PushBack(item)
+	q.notEmpty.Signal()
+	return nil
+}
+
+// Pop removes the item at the front of the queue, waiting for one to be pushed
+// if the queue is empty. Items pushed before the queue was closed are still
+// returned after it was closed; ok is false once the queue is closed and
+// empty.
+//
+// Complexity: O(1)
+func (q *Blocking[T]) Pop() (item T, ok bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	for !q.closed && q.items.Len() == 0 {
+		q.notEmpty.Wait()
+	}
+	if q.items.Len() == 0 {
+		return item, false
+	}
+	item = q.items.Remove(q.items.Front())
+	q.notFull.Signal()
+	return item, true
+}
+
+// Close closes the queue, waking up all goroutines blocked in Push or Pop.
+// Closing a queue more than once has no effect.
+func (q *Blocking[T]) Close() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.closed = true
+	q.notEmpty.Broadcast()
+	q.notFull.Broadcast()
+}
@@ -0,0 +1,65 @@
+package queue
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBlocking(t *testing.T) {
+	q := NewBlocking[int](3)
+	for i := 0; i < 3; i++ {
+		if err := q.Push(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := q.Len(); n != 3 {
+		t.Errorf("wrong queue length: got=%d want=3", n)
+	}
+
+	pushed := make(chan error)
+	go func() { pushed <- q.Push(3) }()
+
+	select {
+	case <-pushed:
+		t.Fatal("push did not block on a full queue")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	if item, ok := q.Pop(); !ok || item != 0 {
+		t.Errorf("wrong item popped: got=%d,%t want=0,true", item, ok)
+	}
+	if err := <-pushed; err != nil {
+		t.Fatal(err)
+	}
+
+	q.Close()
+	if err := q.Push(4); err != ErrClosed {
+		t.Errorf("wrong error pushing to a closed queue: got=%v want=%v", err, ErrClosed)
+	}
+	for want := 1; want <= 3; want++ {
+		if item, ok := q.Pop(); !ok || item != want {
+			t.Errorf("wrong item popped from closed queue: got=%d,%t want=%d,true", item, ok, want)
+		}
+	}
+	if item, ok := q.Pop(); ok {
+		t.Errorf("item popped from closed and empty queue: %d", item)
+	}
+}
+
+func TestBlockingCloseWakesPop(t *testing.T) {
+	q := NewBlocking[int](1)
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if item, ok := q.Pop(); ok {
+				t.Errorf("item popped from empty queue: %d", item)
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	q.Close()
+	wg.Wait()
+}
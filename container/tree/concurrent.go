@@ -0,0 +1,137 @@
+package tree
+
+import "sync"
+
+// ConcurrentMap is an ordered map safe to use concurrently from multiple
+// goroutines.
+//
+// The keys are distributed across a set of shards by a hash function, each
+// shard being a Map guarded by its own read-write mutex. Operations on keys
+// which hash to different shards do not contend with each other, which allows
+// the map to scale better than a single Map guarded by a mutex when accessed
+// by many goroutines.
+//
+// Since keys are spread across shards, ordered iterations must merge the
+// entries of all the shards, see Range for details.
+//
+// ConcurrentMap values must be constructed by a call to NewConcurrentMap.
+type ConcurrentMap[K, V any] struct {
+	cmp    func(K, K) int
+	hash   func(K) uint64
+	shards []concurrentShard[K, V]
+}
+
+type concurrentShard[K, V any] struct {
+	mutex sync.RWMutex
+	impl  Map[K, V]
+	// Pad shards to avoid false sharing of the mutexes of adjacent shards.
+	_ [64]byte
+}
+
+// NewConcurrentMap constructs a map ordered by the cmp comparison function,
+// distributing keys across the given number of shards using the hash function.
+//
+// The number of shards is rounded up to a power of two. Keys comparing equal
+// must have the same hash.
+func NewConcurrentMap[K, V any](cmp func(K, K) int, hash func(K) uint64, shards int) *ConcurrentMap[K, V] {
+	n := 1
+	for n < shards {
+		n *= 2
+	}
+	m := &ConcurrentMap[K, V]{
+		cmp:    cmp,
+		hash:   hash,
+		shards: make([]concurrentShard[K, V], n),
+	}
+	for i := range m.shards {
+		m.shards[i].impl.Init(cmp)
+	}
+	return m
+}
+
+func (m *ConcurrentMap[K, V]) shardOf(key K) *concurrentShard[K, V] {
+	return &m.shards[m.hash(key)&uint64(len(m.shards)-1)]
+}
+
+// Len returns the number of entries in the map. Shards are counted one at a
+// time, the result may not reflect the state of the map at a single point in
+// time if it is being modified concurrently.
+//
+// Complexity: O(s) with s being the number of shards
+func (m *ConcurrentMap[K, V]) Len() int {
+	n := 0
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.mutex.RLock()
+		n += s.impl.Len()
+		s.mutex.RUnlock()
+	}
+	return n
+}
+
+// Insert inserts or replaces a value associated with a key in the map. The
+// method returns the previous value associated with the key and true if it
+// replaced one, or the zero-value and false otherwise.
+//
+// Complexity: O(log n)
+func (m *ConcurrentMap[K, V]) Insert(key K, value V) (previous V, replaced bool) {
+	s := m.shardOf(key)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.impl.Insert(key, value)
+}
+
+// Lookup returns the value associated with the given key in the map, and a
+// boolean value indicating whether the key was found in the map.
+//
+// Complexity: O(log n)
+func (m *ConcurrentMap[K, V]) Lookup(key K) (value V, found bool) {
+	s := m.shardOf(key)
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.impl.Lookup(key)
+}
+
+// Delete deletes the entry associated with the key passed as argument,
+// returning the value it was associated with and true if the key existed in
+// the map, or the zero-value and false otherwise.
+//
+// Complexity: O(log n)
+func (m *ConcurrentMap[K, V]) Delete(key K) (value V, deleted bool) {
+	s := m.shardOf(key)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.impl.Delete(key)
+}
+
+// Range calls f for each entry of the map with a key greater or equal to min,
+// in ascending order. If f returns false, the iteration is stopped.
+//
+// The entries of each shard are snapshotted while holding the read lock of the
+// shard, then merged, so f can safely call methods of the map. The view is
+// consistent within each shard but not across shards: a concurrent insertion
+// or deletion may be observed in one shard and not in another.
+//
+// Complexity: O(n log s) with n being the number of entries with keys greater
+// or equal to min, and s the number of shards
+func (m *ConcurrentMap[K, V]) Range(min K, f func(K, V) bool) {
+	snapshots := make([]*Map[K, V], len(m.shards))
+	var entries []Entry[K, V]
+	for i := range m.shards {
+		entries = m.shards[i].snapshot(min, entries[:0])
+		snapshot := NewMap[K, V](m.cmp)
+		snapshot.build(len(entries), func(i int) (K, V) { return entries[i].Key, entries[i].Value })
+		snapshots[i] = snapshot
+	}
+	MergeRange(m.cmp, nil, f, snapshots...)
+}
+
+func (s *concurrentShard[K, V]) snapshot(min K, entries []Entry[K, V]) []Entry[K, V] {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	s.impl.Range(min, func(k K, v V) bool {
+		entries = append(entries, Entry[K, V]{Key: k, Value: v})
+		return true
+	})
+	return entries
+}
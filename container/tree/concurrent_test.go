@@ -0,0 +1,133 @@
+package tree
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/segmentio/datastructures/v2/compare"
+)
+
+func hashInt(i int) uint64 {
+	// Fibonacci hashing spreads sequential integers across shards.
+	return uint64(i) * 0x9E3779B97F4A7C15 >> 32
+}
+
+func TestConcurrentMap(t *testing.T) {
+	m := NewConcurrentMap[int, int](compare.Function[int], hashInt, 7)
+	if n := len(m.shards); n != 8 {
+		t.Errorf("wrong number of shards: got=%d want=8", n)
+	}
+
+	for i := 0; i < 100; i++ {
+		m.Insert(i, -i)
+	}
+	if n := m.Len(); n != 100 {
+		t.Errorf("wrong map length: got=%d want=100", n)
+	}
+	if v, ok := m.Lookup(42); !ok || v != -42 {
+		t.Errorf("wrong value for key=42: got=%d,%t want=-42,true", v, ok)
+	}
+	if prev, ok := m.Insert(42, 0); !ok || prev != -42 {
+		t.Errorf("wrong previous value for key=42: got=%d,%t want=-42,true", prev, ok)
+	}
+	for i := 0; i < 100; i += 2 {
+		if _, ok := m.Delete(i); !ok {
+			t.Errorf("key not deleted: %d", i)
+		}
+	}
+	if _, ok := m.Lookup(42); ok {
+		t.Error("deleted key found in map")
+	}
+
+	keys := []int{}
+	m.Range(80, func(k, v int) bool {
+		if v != -k {
+			t.Errorf("wrong value for key=%d: got=%d want=%d", k, v, -k)
+		}
+		keys = append(keys, k)
+		return len(keys) < 5
+	})
+	if fmt.Sprint(keys) != "[81 83 85 87 89]" {
+		t.Errorf("wrong keys in range: got=%v want=[81 83 85 87 89]", keys)
+	}
+}
+
+func TestConcurrentMapParallel(t *testing.T) {
+	const N = 1000
+	m := NewConcurrentMap[int, int](compare.Function[int], hashInt, 16)
+
+	wg := sync.WaitGroup{}
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := g; i < N; i += 8 {
+				m.Insert(i, i)
+				m.Lookup(rand.Intn(N))
+			}
+			m.Range(0, func(k, v int) bool { return true })
+		}(g)
+	}
+	wg.Wait()
+
+	prev, n := -1, 0
+	m.Range(0, func(k, v int) bool {
+		if k <= prev {
+			t.Errorf("keys out of order: %d after %d", k, prev)
+		}
+		prev = k
+		n++
+		return true
+	})
+	if n != N {
+		t.Errorf("wrong number of keys in range: got=%d want=%d", n, N)
+	}
+}
+
+func BenchmarkConcurrentMap(b *testing.B) {
+	loads := []struct {
+		scenario string
+		writes   int // percentage of operations which are writes
+	}{
+		{scenario: "read-heavy", writes: 10},
+		{scenario: "write-heavy", writes: 90},
+	}
+
+	for _, load := range loads {
+		b.Run(load.scenario, func(b *testing.B) {
+			b.Run("locked", func(b *testing.B) {
+				m := NewSyncMap[int, int](compare.Function[int])
+				insert := func(k, v int) { m.Insert(k, v) }
+				lookup := func(k int) { m.Lookup(k) }
+				benchmarkConcurrentLoad(b, load.writes, insert, lookup)
+			})
+			b.Run("sharded", func(b *testing.B) {
+				m := NewConcurrentMap[int, int](compare.Function[int], hashInt, 64)
+				insert := func(k, v int) { m.Insert(k, v) }
+				lookup := func(k int) { m.Lookup(k) }
+				benchmarkConcurrentLoad(b, load.writes, insert, lookup)
+			})
+		})
+	}
+}
+
+func benchmarkConcurrentLoad(b *testing.B, writes int, insert func(int, int), lookup func(int)) {
+	const N = 1 << 16
+	for i := 0; i < N; i++ {
+		insert(i, i)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		prng := rand.New(rand.NewSource(rand.Int63()))
+		for pb.Next() {
+			key := prng.Intn(N)
+			if prng.Intn(100) < writes {
+				insert(key, key)
+			} else {
+				lookup(key)
+			}
+		}
+	})
+}
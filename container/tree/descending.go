@@ -0,0 +1,102 @@
+package tree
+
+// DescendingMap is a view of a Map presenting its entries in the reverse of the
+// order defined by the comparison function of the map.
+//
+// The view does not copy the map, modifications of the map are visible through
+// the view.
+type DescendingMap[K, V any] struct{ m *Map[K, V] }
+
+// Descending returns a view of the map presenting its entries in descending
+// order.
+//
+// Complexity: O(1)
+func (m *Map[K, V]) Descending() DescendingMap[K, V] { return DescendingMap[K, V]{m} }
+
+// Ascending returns the map that the view was created from.
+//
+// Complexity: O(1)
+func (d DescendingMap[K, V]) Ascending() *Map[K, V] { return d.m }
+
+// Len returns the number of entries in the map.
+//
+// Complexity: O(1)
+func (d DescendingMap[K, V]) Len() int { return d.m.Len() }
+
+// Range calls f for each entry of the map for each key less or equal to the
+// max key passed as first argument. The keys and values are presented in
+// descending order according to the comparison function installed on the map.
+//
+// Complexity: O(log n) + O(k) with k being the number of calls to f
+func (d DescendingMap[K, V]) Range(max K, f func(K, V) bool) {
+	if d.m.root != nil {
+		d.m.findAndRangeReverse(d.m.root, max, f)
+	}
+}
+
+// Lookup returns the value associated with the given key in the map, and a
+// boolean value indicating whether the key was found in the map.
+//
+// Complexity: O(log n)
+func (d DescendingMap[K, V]) Lookup(key K) (value V, found bool) { return d.m.Lookup(key) }
+
+// Min returns the first entry of the view, which is the entry with the largest
+// key in the map.
+//
+// Complexity: O(log n)
+func (d DescendingMap[K, V]) Min() (key K, value V, found bool) { return d.m.Max() }
+
+// Max returns the last entry of the view, which is the entry with the smallest
+// key in the map.
+//
+// Complexity: O(log n)
+func (d DescendingMap[K, V]) Max() (key K, value V, found bool) { return d.m.Min() }
+
+// DescendingTree is a view of a Tree presenting its elements in the reverse of
+// the order defined by the comparison function of the tree.
+//
+// The view does not copy the tree, modifications of the tree are visible
+// through the view.
+type DescendingTree[E any] struct{ t *Tree[E] }
+
+// Descending returns a view of the tree presenting its elements in descending
+// order.
+//
+// Complexity: O(1)
+func (t *Tree[E]) Descending() DescendingTree[E] { return DescendingTree[E]{t} }
+
+// Ascending returns the tree that the view was created from.
+//
+// Complexity: O(1)
+func (d DescendingTree[E]) Ascending() *Tree[E] { return d.t }
+
+// Len returns the number of elements in the tree.
+//
+// Complexity: O(1)
+func (d DescendingTree[E]) Len() int { return d.t.Len() }
+
+// Range calls f for each element of the tree less or equal to max, in the
+// reverse of the order defined by the comparison function. If f returns false,
+// the iteration is stopped.
+//
+// Complexity: O(log n) + O(k) with k being the number of calls to f
+func (d DescendingTree[E]) Range(max E, f func(E) bool) {
+	d.t.impl.Descending().Range(max, func(elem E, _ struct{}) bool { return f(elem) })
+}
+
+// Contains returns true if the given element exists in the tree.
+//
+// Complexity: O(log n)
+func (d DescendingTree[E]) Contains(elem E) bool { return d.t.Contains(elem) }
+
+// Min returns the first element of the view, which is the largest element of
+// the tree.
+//
+// Complexity: O(log n)
+func (d DescendingTree[E]) Min() (min E, found bool) { return d.t.Max() }
+
+// Max returns the last element of the view, which is the smallest element of
+// the tree.
+//
+// Complexity: O(log n)
+func (d DescendingTree[E]) Max() (max E, found bool) { return d.t.Min() }
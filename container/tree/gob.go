@@ -0,0 +1,59 @@
+package tree
+
+import (
+	"bytes"
+	"encoding/gob"
+)
+
+// GobEncode implements the gob.GobEncoder interface, encoding the entries of
+// the map in ascending key order. The comparison function is not encoded.
+//
+// Complexity: O(n)
+func (m *Map[K, V]) GobEncode() ([]byte, error) {
+	entries := make([]Entry[K, V], 0, m.len)
+	if m.root != nil {
+		m.rangeFrom(m.root, func(key K, value V) bool {
+			entries = append(entries, Entry[K, V]{Key: key, Value: value})
+			return true
+		})
+	}
+	b := new(bytes.Buffer)
+	if err := gob.NewEncoder(b).Encode(entries); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// GobDecode implements the gob.GobDecoder interface, replacing the content of
+// the map with the decoded entries.
+//
+// Since comparison functions cannot be encoded, the map must have been
+// initialized prior to decoding, the method panics otherwise. If the entries
+// are in ascending order according to the comparison function of the map, the
+// tree is built in a single pass, otherwise they are inserted one by one.
+//
+// Complexity: O(n) if the entries are sorted, O(n log n) otherwise
+func (m *Map[K, V]) GobDecode(data []byte) error {
+	if m.cmp == nil {
+		panic("tree: GobDecode called on a map which was not initialized")
+	}
+	var entries []Entry[K, V]
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&entries); err != nil {
+		return err
+	}
+
+	sorted := true
+	for i := 1; i < len(entries) && sorted; i++ {
+		sorted = m.cmp(entries[i-1].Key, entries[i].Key) < 0
+	}
+
+	m.Clear()
+	if sorted {
+		m.build(len(entries), func(i int) (K, V) { return entries[i].Key, entries[i].Value })
+	} else {
+		for _, e := range entries {
+			m.Insert(e.Key, e.Value)
+		}
+	}
+	return nil
+}
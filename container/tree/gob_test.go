@@ -0,0 +1,67 @@
+package tree
+
+import (
+	"bytes"
+	"encoding/gob"
+	"testing"
+
+	"github.com/segmentio/datastructures/v2/compare"
+)
+
+func TestMapGob(t *testing.T) {
+	eq := func(a, b string) bool { return a == b }
+	reverse := func(a, b int) int { return compare.Function(b, a) }
+
+	tests := []struct {
+		scenario string
+		cmp      func(int, int) int
+		size     int
+	}{
+		{scenario: "empty map", cmp: compare.Function[int], size: 0},
+		{scenario: "sorted entries", cmp: compare.Function[int], size: 100},
+		{scenario: "entries decoded in a different order", cmp: reverse, size: 100},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			m := NewMap[int, string](compare.Function[int])
+			want := NewMap[int, string](test.cmp)
+			for i := 0; i < test.size; i++ {
+				m.Insert(i, string(rune('a'+i%26)))
+				want.Insert(i, string(rune('a'+i%26)))
+			}
+
+			b := new(bytes.Buffer)
+			if err := gob.NewEncoder(b).Encode(m); err != nil {
+				t.Fatal(err)
+			}
+
+			decoded := NewMap[int, string](test.cmp)
+			decoded.Insert(-1, "stale")
+			if err := gob.NewDecoder(b).Decode(decoded); err != nil {
+				t.Fatal(err)
+			}
+			decoded.checkInvariants()
+
+			if !decoded.Equal(want, eq) {
+				t.Errorf("decoded map differs from the original: got=%v want=%v", decoded.Keys(), want.Keys())
+			}
+		})
+	}
+}
+
+func TestMapGobDecodeUninitialized(t *testing.T) {
+	m := NewMap[int, int](compare.Function[int])
+	m.Insert(1, 1)
+	data, err := m.GobEncode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("decoding into an uninitialized map did not panic")
+		}
+	}()
+	new(Map[int, int]).GobDecode(data)
+}
@@ -0,0 +1,43 @@
+//go:build go1.23
+
+package tree
+
+import "iter"
+
+// All returns an iterator over the entries of the map in ascending key order.
+//
+// Complexity: O(log n) + O(k) with k being the number of entries visited
+func (m *Map[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		if m.root != nil {
+			m.rangeFrom(m.root, yield)
+		}
+	}
+}
+
+// Backward returns an iterator over the entries of the map in descending key
+// order.
+//
+// Complexity: O(log n) + O(k) with k being the number of entries visited
+func (m *Map[K, V]) Backward() iter.Seq2[K, V] {
+	return m.RangeReverse
+}
+
+// MergeSeq returns an iterator performing a k-way merge of the maps passed as
+// arguments, yielding each key present in any of the maps in ascending order
+// according to the cmp comparison function. The maps must all be ordered by a
+// comparison function equivalent to cmp.
+//
+// When a key exists in several maps, it is yielded once with the value from
+// the first map holding the key, in the order the maps were passed. Use
+// MergeRange to combine the values instead.
+//
+// The maps must not be modified while the iterator is in use.
+//
+// Complexity: O(k log m) with k being the number of entries visited, and m the
+// number of maps
+func MergeSeq[K, V any](cmp func(K, K) int, maps ...*Map[K, V]) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		MergeRange(cmp, nil, yield, maps...)
+	}
+}
@@ -0,0 +1,109 @@
+//go:build go1.23
+
+package tree
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/segmentio/datastructures/v2/compare"
+)
+
+func TestMapAll(t *testing.T) {
+	m := NewMap[int, int](compare.Function[int])
+	for i := 0; i < 10; i++ {
+		m.Insert(i, -i)
+	}
+
+	keys := []int{}
+	for k, v := range m.All() {
+		if v != -k {
+			t.Errorf("wrong value for key=%d: got=%d want=%d", k, v, -k)
+		}
+		keys = append(keys, k)
+	}
+	if fmt.Sprint(keys) != "[0 1 2 3 4 5 6 7 8 9]" {
+		t.Errorf("wrong keys: got=%v want=[0 1 2 3 4 5 6 7 8 9]", keys)
+	}
+
+	keys = keys[:0]
+	for k := range m.All() {
+		if k == 3 {
+			break
+		}
+		keys = append(keys, k)
+	}
+	if fmt.Sprint(keys) != "[0 1 2]" {
+		t.Errorf("wrong keys after break: got=%v want=[0 1 2]", keys)
+	}
+	m.checkInvariants()
+
+	for k := range new(Map[int, int]).All() {
+		t.Errorf("iterator of empty map yielded key=%d", k)
+	}
+}
+
+func TestMapBackward(t *testing.T) {
+	m := NewMap[int, int](compare.Function[int])
+	for i := 0; i < 10; i++ {
+		m.Insert(i, -i)
+	}
+
+	keys := []int{}
+	for k, v := range m.Backward() {
+		if v != -k {
+			t.Errorf("wrong value for key=%d: got=%d want=%d", k, v, -k)
+		}
+		keys = append(keys, k)
+	}
+	if fmt.Sprint(keys) != "[9 8 7 6 5 4 3 2 1 0]" {
+		t.Errorf("wrong keys: got=%v want=[9 8 7 6 5 4 3 2 1 0]", keys)
+	}
+
+	keys = keys[:0]
+	for k := range m.Backward() {
+		if k == 6 {
+			break
+		}
+		keys = append(keys, k)
+	}
+	if fmt.Sprint(keys) != "[9 8 7]" {
+		t.Errorf("wrong keys after break: got=%v want=[9 8 7]", keys)
+	}
+	m.checkInvariants()
+}
+
+func TestMergeSeq(t *testing.T) {
+	m1 := NewMap[int, string](compare.Function[int])
+	m2 := NewMap[int, string](compare.Function[int])
+	empty := new(Map[int, string])
+
+	for i := 0; i < 10; i += 2 {
+		m1.Insert(i, "a")
+	}
+	for i := 0; i < 10; i += 3 {
+		m2.Insert(i, "b")
+	}
+
+	got := []string{}
+	for k, v := range MergeSeq(compare.Function[int], m1, empty, m2) {
+		got = append(got, fmt.Sprintf("%d:%s", k, v))
+	}
+	if s := fmt.Sprint(got); s != "[0:a 2:a 3:b 4:a 6:a 8:a 9:b]" {
+		t.Errorf("wrong merged entries: got=%s want=[0:a 2:a 3:b 4:a 6:a 8:a 9:b]", s)
+	}
+
+	n := 0
+	for range MergeSeq(compare.Function[int], m1, m2) {
+		if n++; n == 3 {
+			break
+		}
+	}
+	if n != 3 {
+		t.Errorf("wrong number of entries after breaking: got=%d want=3", n)
+	}
+
+	for k := range MergeSeq[int, string](compare.Function[int]) {
+		t.Errorf("unexpected key=%d when merging no maps", k)
+	}
+}
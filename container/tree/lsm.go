@@ -0,0 +1,211 @@
+package tree
+
+import (
+	"math/bits"
+	"sort"
+)
+
+// DefaultLSMBufferSize is the default size of the write buffer of LSMMap
+// instances.
+const DefaultLSMBufferSize = 1024
+
+// LSMMap is an ordered map optimized for write-heavy workloads.
+//
+// Writes are appended to an unsorted buffer, which is merged into a Map when it
+// reaches its configured size. Merging sorts the writes, which only applies the
+// last write to each key, and rebuilds the tree in a single pass when the batch
+// is large enough for this to be cheaper than rebalancing the tree on each
+// write (see Flush). Reads must search the buffer in addition to the tree.
+//
+// Len and Range merge the write buffer before reading the tree, which means
+// that unlike Lookup they modify the map, and must not be called concurrently
+// with other methods.
+//
+// The zero-value is a valid empty map which supports lookups, but must be
+// initialized prior to inserting or deleting keys.
+type LSMMap[K, V any] struct {
+	tree   Map[K, V]
+	buffer []lsmEntry[K, V]
+	size   int
+}
+
+type lsmEntry[K, V any] struct {
+	key     K
+	value   V
+	deleted bool
+}
+
+// NewLSMMap instantiates a new map using the given comparison function to order
+// the keys, and buffering up to bufferSize writes before merging them.
+func NewLSMMap[K, V any](cmp func(K, K) int, bufferSize int) *LSMMap[K, V] {
+	m := new(LSMMap[K, V])
+	m.Init(cmp, bufferSize)
+	return m
+}
+
+// Init initializes (or re-initializes) the map. The comparison function passed
+// as argument will be used to order the keys, and up to bufferSize writes are
+// buffered before being merged. If bufferSize is zero or negative,
+// DefaultLSMBufferSize is used.
+//
+// Complexity: O(1)
+func (m *LSMMap[K, V]) Init(cmp func(K, K) int, bufferSize int) {
+	if bufferSize <= 0 {
+		bufferSize = DefaultLSMBufferSize
+	}
+	m.tree.Init(cmp)
+	m.buffer = make([]lsmEntry[K, V], 0, bufferSize)
+	m.size = bufferSize
+}
+
+// Len returns the number of entries currently held in the map. The write buffer
+// is merged prior to counting the entries, see Flush.
+//
+// Complexity: O(1), or the complexity of Flush when writes are buffered
+func (m *LSMMap[K, V]) Len() int {
+	m.Flush()
+	return m.tree.Len()
+}
+
+// Insert inserts a new entry in the map, or replaces the value if the key
+// already existed.
+//
+// Complexity: O(1), or the complexity of Flush when the buffer is merged
+func (m *LSMMap[K, V]) Insert(key K, value V) {
+	m.write(lsmEntry[K, V]{key: key, value: value})
+}
+
+// Delete deletes the given key from the map.
+//
+// Complexity: O(1), or the complexity of Flush when the buffer is merged
+func (m *LSMMap[K, V]) Delete(key K) {
+	m.write(lsmEntry[K, V]{key: key, deleted: true})
+}
+
+func (m *LSMMap[K, V]) write(e lsmEntry[K, V]) {
+	if m.tree.cmp == nil {
+		panic("tree: write to uninitialized LSMMap")
+	}
+	m.buffer = append(m.buffer, e)
+	if len(m.buffer) >= m.size {
+		m.Flush()
+	}
+}
+
+// Lookup returns the value associated with the given key in the map, and a
+// boolean value indicating whether the key was found in the map. The most
+// recent buffered writes are searched first, then the tree.
+//
+// Complexity: O(b) + O(log n)
+func (m *LSMMap[K, V]) Lookup(key K) (value V, found bool) {
+	for i := len(m.buffer) - 1; i >= 0; i-- {
+		if e := &m.buffer[i]; m.tree.cmp(key, e.key) == 0 {
+			if e.deleted {
+				return value, false
+			}
+			return e.value, true
+		}
+	}
+	return m.tree.Lookup(key)
+}
+
+// Range calls f for each entry of the map for each key greater or equal to the
+// min key passed as first argument, in ascending order. The write buffer is
+// merged prior to iterating over the entries, see Flush.
+//
+// Complexity: O(log n) + O(k) with k being the number of calls to f, plus the
+// complexity of Flush when writes are buffered
+func (m *LSMMap[K, V]) Range(min K, f func(K, V) bool) {
+	m.Flush()
+	m.tree.Range(min, f)
+}
+
+// Flush merges the buffered writes into the tree.
+//
+// The writes are sorted so only the last write to each key is applied. Small
+// batches are applied by inserting and deleting keys one by one. When the batch
+// is large relative to the tree, the writes are instead merged with an in-order
+// walk of the tree into a list of nodes from which the tree is rebuilt, which
+// costs less than rebalancing the tree after each write.
+//
+// Complexity: O(b log b) + O(min(b log n, n)) with b being the number of
+// buffered writes
+func (m *LSMMap[K, V]) Flush() {
+	if len(m.buffer) == 0 {
+		return
+	}
+	t := &m.tree
+
+	// The stable sort retains the order of writes to the same key, so only
+	// the last one needs to be applied.
+	sort.SliceStable(m.buffer, func(i, j int) bool {
+		return t.cmp(m.buffer[i].key, m.buffer[j].key) < 0
+	})
+
+	writes := m.buffer[:0]
+	for i := range m.buffer {
+		if i+1 < len(m.buffer) && t.cmp(m.buffer[i].key, m.buffer[i+1].key) == 0 {
+			continue
+		}
+		writes = append(writes, m.buffer[i])
+	}
+
+	if len(writes)*bits.Len(uint(t.len)) < t.len {
+		m.apply(writes)
+	} else {
+		m.rebuild(writes)
+	}
+
+	for i := range m.buffer {
+		m.buffer[i] = lsmEntry[K, V]{} // release references to keys and values
+	}
+	m.buffer = m.buffer[:0]
+}
+
+// apply writes the entries to the tree one by one.
+func (m *LSMMap[K, V]) apply(writes []lsmEntry[K, V]) {
+	for i := range writes {
+		if e := &writes[i]; e.deleted {
+			m.tree.Delete(e.key)
+		} else {
+			m.tree.Insert(e.key, e.value)
+		}
+	}
+}
+
+// rebuild merges the entries, which must be sorted with no duplicate keys,
+// with the nodes of the tree and rebuilds it.
+func (m *LSMMap[K, V]) rebuild(writes []lsmEntry[K, V]) {
+	t := &m.tree
+	t.version++
+	nodes := make([]*node[K, V], 0, t.len+len(writes))
+	insert := func(e *lsmEntry[K, V]) {
+		if !e.deleted {
+			nodes = append(nodes, &node[K, V]{key: e.key, value: e.value, version: t.version, epoch: t.epoch})
+		}
+	}
+
+	i := 0
+	t.rangeNodes(t.root, func(n *node[K, V]) {
+		for i < len(writes) && t.cmp(writes[i].key, n.key) < 0 {
+			insert(&writes[i])
+			i++
+		}
+		if i < len(writes) && t.cmp(writes[i].key, n.key) == 0 {
+			e := &writes[i]
+			i++
+			if e.deleted {
+				return
+			}
+			n = t.own(n)
+			n.value, n.version = e.value, t.version
+		} else {
+			n = t.own(n)
+		}
+		nodes = append(nodes, n)
+	})
+	for ; i < len(writes); i++ {
+		insert(&writes[i])
+	}
+	t.link(nodes)
+}
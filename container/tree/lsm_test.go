@@ -0,0 +1,147 @@
+package tree
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/segmentio/datastructures/v2/compare"
+)
+
+func TestLSMMap(t *testing.T) {
+	for _, bufferSize := range []int{1, 7, 64} {
+		m := NewLSMMap[int, int](compare.Function[int], bufferSize)
+		model := make(map[int]int)
+		prng := rand.New(rand.NewSource(int64(bufferSize)))
+
+		for i := 0; i < 2000; i++ {
+			k := prng.Intn(200)
+			if prng.Intn(3) == 0 {
+				m.Delete(k)
+				delete(model, k)
+			} else {
+				m.Insert(k, i)
+				model[k] = i
+			}
+
+			k = prng.Intn(200)
+			v, found := m.Lookup(k)
+			want, exist := model[k]
+			if found != exist || v != want {
+				t.Fatalf("buffer size %d: wrong lookup result for key=%d: got=%d/%t want=%d/%t", bufferSize, k, v, found, want, exist)
+			}
+		}
+
+		if n := m.Len(); n != len(model) {
+			t.Errorf("buffer size %d: wrong number of entries: got=%d want=%d", bufferSize, n, len(model))
+		}
+
+		prev := math.MinInt
+		m.Range(math.MinInt, func(k, v int) bool {
+			if k <= prev {
+				t.Errorf("buffer size %d: keys out of order: %d <= %d", bufferSize, k, prev)
+			}
+			if v != model[k] {
+				t.Errorf("buffer size %d: wrong value for key=%d: got=%d want=%d", bufferSize, k, v, model[k])
+			}
+			prev = k
+			return true
+		})
+
+		m.tree.checkInvariants()
+	}
+}
+
+func TestLSMMapFlush(t *testing.T) {
+	// The writes are applied one by one to the large tree, and merged by
+	// rebuilding the small one.
+	for _, size := range []int{100, 10000} {
+		m := NewLSMMap[int, int](compare.Function[int], 2*size)
+		model := make(map[int]int)
+		for i := 0; i < size; i++ {
+			m.Insert(i, i)
+			model[i] = i
+		}
+		m.Flush()
+
+		// Overwrite, delete, and insert keys before, within, and after the
+		// range of keys already in the tree, with several writes to some of
+		// the keys.
+		for i := -50; i < 150; i++ {
+			switch {
+			case i%3 == 0:
+				m.Insert(i, -i)
+				m.Delete(i)
+				delete(model, i)
+			case i%2 == 0:
+				m.Delete(i)
+				m.Insert(i, -i)
+				model[i] = -i
+			}
+		}
+		if n := len(m.buffer); n == 0 {
+			t.Fatalf("size %d: writes were not buffered", size)
+		}
+		m.Flush()
+
+		if n := len(m.buffer); n != 0 {
+			t.Errorf("size %d: wrong number of buffered writes after flush: got=%d want=0", size, n)
+		}
+		if n := m.tree.Len(); n != len(model) {
+			t.Errorf("size %d: wrong number of entries: got=%d want=%d", size, n, len(model))
+		}
+		for k, want := range model {
+			if v, ok := m.tree.Lookup(k); !ok || v != want {
+				t.Errorf("size %d: wrong value for key=%d: got=%d,%t want=%d,true", size, k, v, ok, want)
+			}
+		}
+		m.tree.checkInvariants()
+	}
+}
+
+func BenchmarkLSMInsert(b *testing.B) {
+	const N = 1024
+	m := NewLSMMap[int, int](compare.Function[int], 0)
+
+	for i := 0; i < b.N; i++ {
+		m.Insert(i%N, i)
+	}
+}
+
+// BenchmarkLSMFlush compares the two strategies of merging a batch of writes in
+// the tree, which Flush selects between when b log n crosses n.
+func BenchmarkLSMFlush(b *testing.B) {
+	for _, size := range []int{1000, 100000} {
+		for _, batch := range []int{16, 512, 16384} {
+			if batch > size {
+				continue
+			}
+			m := NewLSMMap[int, int](compare.Function[int], 0)
+			for i := 0; i < size; i++ {
+				m.tree.Insert(i, i)
+			}
+			prng := rand.New(rand.NewSource(0))
+			writes := make([]lsmEntry[int, int], 0, batch)
+			for _, k := range prng.Perm(size)[:batch] {
+				writes = append(writes, lsmEntry[int, int]{key: k, value: -k})
+			}
+			sort.Slice(writes, func(i, j int) bool { return writes[i].key < writes[j].key })
+
+			for _, strategy := range []struct {
+				name  string
+				flush func([]lsmEntry[int, int])
+			}{
+				{"apply", m.apply},
+				{"rebuild", m.rebuild},
+			} {
+				b.Run(fmt.Sprintf("n=%d/b=%d/%s", size, len(writes), strategy.name), func(b *testing.B) {
+					for i := 0; i < b.N; i++ {
+						strategy.flush(writes)
+					}
+				})
+			}
+		}
+	}
+}
@@ -1,5 +1,17 @@
 package tree
 
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"math/rand"
+	"strconv"
+	"sync/atomic"
+	"unsafe"
+)
+
 /*
 	The red-black tree implementation in this file was derived from
 	https://github.com/PratikDeoghare/redblack
@@ -30,6 +42,17 @@ OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
 SOFTWARE.
 */
 
+// Entry is a key and value pair of a map.
+type Entry[K, V any] struct {
+	Key   K
+	Value V
+}
+
+// ErrKeyMismatch is returned by InsertStrict when the comparison function of a
+// strict map reports a key as equal to an existing key which is not identical
+// according to the equal function.
+var ErrKeyMismatch = errors.New("tree: comparison function reports distinct keys as equal")
+
 // Map is a map type associating keys to values in a similar way to the standard
 // Go map type, but backed by a balanced binary tree instead of a hashmap, which
 // maintains ordering of keys.
@@ -37,13 +60,24 @@ SOFTWARE.
 // The zero-value is a valid empty map which supports lookups and deletes, but
 // must be initialized prior to inserting any keys.
 type Map[K, V any] struct {
-	cmp    func(K, K) int
-	len    int
-	root   *node[K, V]
-	leaf   node[K, V] // This leaf always Black. We don't touch it. Its a sacred leaf.
-	bbleaf node[K, V] // This leaf is used for deletion.
+	cmp     func(K, K) int
+	equal   func(K, K) bool
+	len     int
+	version uint64
+	root    *node[K, V]
+	leaf    *node[K, V] // This leaf always Black. We don't touch it. Its a sacred leaf.
+	bbleaf  *node[K, V] // This leaf is used for deletion.
+	// The epoch identifies the nodes owned by the map, which it may modify in
+	// place. Nodes of other epochs are shared with snapshots and must be
+	// copied before being modified.
+	epoch uint64
 }
 
+// epochs is the source of unique epochs assigned to maps and their snapshots.
+var epochs uint64
+
+func nextEpoch() uint64 { return atomic.AddUint64(&epochs, 1) }
+
 type color byte
 
 const (
@@ -53,73 +87,1162 @@ const (
 	nblack color = 3
 )
 
-type node[K, V any] struct {
-	a     *node[K, V]
-	b     *node[K, V]
-	key   K
-	value V // not the last field so it takes no space when set to struct{}
-	color color
+type node[K, V any] struct {
+	a     *node[K, V]
+	b     *node[K, V]
+	key   K
+	value V // not the last field so it takes no space when set to struct{}
+	// The version of the map when the entry was last written, and the largest
+	// version in the subtree rooted at this node, which is maintained by the
+	// update function when the tree is modified.
+	version    uint64
+	maxVersion uint64
+	// The number of nodes in the subtree rooted at this node, also maintained
+	// by the update function, which allows order-statistics queries.
+	size int
+	// The epoch of the map which created the node, see Map.epoch.
+	epoch uint64
+	color color
+}
+
+// NewMap instantiates a new map using the given comparison function to order
+// the keys.
+func NewMap[K, V any](cmp func(K, K) int) *Map[K, V] {
+	m := new(Map[K, V])
+	m.Init(cmp)
+	return m
+}
+
+// NewMapStrict is like NewMap but instantiates a map which verifies that keys
+// reported equal by the comparison function are also equal according to the
+// equal function before replacing entries. See InitStrict for details.
+func NewMapStrict[K, V any](cmp func(K, K) int, equal func(K, K) bool) *Map[K, V] {
+	m := new(Map[K, V])
+	m.InitStrict(cmp, equal)
+	return m
+}
+
+// Init initializes (or re-initializes) the map. The comparison function passed
+// as argument will be used to order the keys.
+//
+// Init must be called prior to inserting keys in the map, otherwise inserts
+// will panic.
+//
+// Complexity: O(1)
+func (m *Map[K, V]) Init(cmp func(K, K) int) {
+	m.leaf = &node[K, V]{color: black}
+	m.leaf.a = m.leaf
+	m.leaf.b = m.leaf
+	m.bbleaf = &node[K, V]{color: bblack, a: m.leaf, b: m.leaf}
+	m.cmp = cmp
+	m.equal = nil
+	m.len = 0
+	m.version = 0
+	m.root = m.leaf
+	m.epoch = nextEpoch()
+}
+
+// InitStrict is like Init but installs an equal function used to verify that
+// keys comparing equal are truly identical.
+//
+// The map always replaces entries when the comparison function returns zero,
+// there is no secondary ordering allowing distinct keys which compare equal to
+// coexist. This is a common source of bugs, for example when a comparison
+// function on composite keys forgets to compare one of the fields. A strict
+// map catches those mistakes: calls to Insert panic when the comparison
+// function reports two keys as equal but the equal function does not. Programs
+// which prefer handling the mismatch as an error can use InsertStrict instead,
+// which leaves the map unchanged and returns ErrKeyMismatch.
+//
+// Complexity: O(1)
+func (m *Map[K, V]) InitStrict(cmp func(K, K) int, equal func(K, K) bool) {
+	m.Init(cmp)
+	m.equal = equal
+}
+
+// Clear removes all entries from the map, retaining the comparison and equal
+// functions it was initialized with. The version of the map keeps increasing,
+// so RangeChangedSince reports entries inserted after the map was cleared.
+//
+// The leaf sentinels of the map are retained, which makes clearing a map
+// cheaper than re-initializing it, see MapPool.
+//
+// Clearing a map which was not initialized has no effect.
+//
+// Complexity: O(1)
+func (m *Map[K, V]) Clear() {
+	if m.root == nil {
+		return
+	}
+	m.root, m.len = m.leaf, 0
+	m.version++
+}
+
+// Len returns the number of entries currently held in the map.
+//
+// Complexity: O(1)
+func (m *Map[K, V]) Len() int { return m.len }
+
+// Range calls f for each entry of the map for each key greater or equal to the
+// min key passed as first argument. The keys and values are presented in
+// ascending order according to the comparison function installed on the map.
+//
+// Complexity: O(log n) + O(k) with k being the number of calls to f
+func (m *Map[K, V]) Range(min K, f func(K, V) bool) {
+	if m.root != nil {
+		m.findAndRange(m.root, min, f)
+	}
+}
+
+// RangeReverse calls f for each entry of the map, in descending key order. If
+// f returns false, the iteration is stopped.
+//
+// Complexity: O(log n) + O(k) with k being the number of calls to f
+func (m *Map[K, V]) RangeReverse(f func(K, V) bool) {
+	if m.root != nil {
+		m.rangeFromReverse(m.root, f)
+	}
+}
+
+func (m *Map[K, V]) findAndRange(n *node[K, V], key K, f func(K, V) bool) bool {
+	if n == m.leaf {
+		return true
+	}
+	switch cmp := m.cmp(key, n.key); {
+	case cmp < 0:
+		return m.findAndRange(n.a, key, f) && f(n.key, n.value) && m.rangeFrom(n.b, f)
+	case cmp > 0:
+		return m.findAndRange(n.b, key, f)
+	default:
+		return f(n.key, n.value) && m.rangeFrom(n.b, f)
+	}
+}
+
+func (m *Map[K, V]) rangeFrom(n *node[K, V], call func(K, V) bool) bool {
+	return n == m.leaf || (m.rangeFrom(n.a, call) && call(n.key, n.value) && m.rangeFrom(n.b, call))
+}
+
+func (m *Map[K, V]) findAndRangeReverse(n *node[K, V], key K, f func(K, V) bool) bool {
+	if n == m.leaf {
+		return true
+	}
+	switch cmp := m.cmp(key, n.key); {
+	case cmp > 0:
+		return m.findAndRangeReverse(n.b, key, f) && f(n.key, n.value) && m.rangeFromReverse(n.a, f)
+	case cmp < 0:
+		return m.findAndRangeReverse(n.a, key, f)
+	default:
+		return f(n.key, n.value) && m.rangeFromReverse(n.a, f)
+	}
+}
+
+func (m *Map[K, V]) rangeFromReverse(n *node[K, V], call func(K, V) bool) bool {
+	return n == m.leaf || (m.rangeFromReverse(n.b, call) && call(n.key, n.value) && m.rangeFromReverse(n.a, call))
+}
+
+// RangeBetween calls f for each entry of the map with a key in the closed
+// interval [lo, hi], in ascending order. If f returns false, the iteration is
+// stopped. The interval is empty if lo is greater than hi.
+//
+// Complexity: O(log n) + O(k) with k being the number of calls to f
+func (m *Map[K, V]) RangeBetween(lo, hi K, f func(K, V) bool) {
+	if m.root != nil && m.cmp(lo, hi) <= 0 {
+		m.findAndRange(m.root, lo, func(k K, v V) bool {
+			return m.cmp(k, hi) <= 0 && f(k, v)
+		})
+	}
+}
+
+// RangeReverseFrom calls f for each entry of the map with a key in the closed
+// interval [lo, hi], in descending order. If f returns false, the iteration is
+// stopped.
+//
+// This is useful to walk backward from a bound, for example to collect the
+// last entries recorded before a point in time.
+//
+// Complexity: O(log n) + O(k) with k being the number of calls to f
+func (m *Map[K, V]) RangeReverseFrom(lo, hi K, f func(K, V) bool) {
+	if m.root != nil && m.cmp(lo, hi) <= 0 {
+		m.findAndRangeReverse(m.root, hi, func(k K, v V) bool {
+			return m.cmp(k, lo) >= 0 && f(k, v)
+		})
+	}
+}
+
+// EqualRange calls f for each entry of the map with a key comparing equal to
+// key under the coarse comparison function, in ascending order. If f returns
+// false, the iteration is stopped.
+//
+// The coarse comparison function must be consistent with the ordering of the
+// map, grouping keys which are adjacent in the map (e.g. comparing only the
+// date part of timestamps), so that the matching entries form a contiguous
+// range.
+//
+// Complexity: O(log n) + O(k) with k being the number of calls to f
+func (m *Map[K, V]) EqualRange(key K, coarse func(K, K) int, f func(K, V) bool) {
+	if m.root != nil {
+		m.findCoarseAndRange(m.root, key, coarse, func(k K, v V) bool {
+			return coarse(k, key) == 0 && f(k, v)
+		})
+	}
+}
+
+func (m *Map[K, V]) findCoarseAndRange(n *node[K, V], key K, coarse func(K, K) int, f func(K, V) bool) bool {
+	if n == m.leaf {
+		return true
+	}
+	if coarse(key, n.key) > 0 {
+		return m.findCoarseAndRange(n.b, key, coarse, f)
+	}
+	return m.findCoarseAndRange(n.a, key, coarse, f) && f(n.key, n.value) && m.rangeFrom(n.b, f)
+}
+
+// IntersectSorted calls f for each entry of the map with a key present in the
+// keys slice, in ascending order. The slice must be sorted according to the
+// comparison function of the map. If f returns false, the iteration is stopped.
+//
+// The map and the slice are traversed in lockstep, which is more efficient than
+// looking up each key individually when joining large sets of keys.
+//
+// Complexity: O(log n) + O(n + m) with m being the length of the slice
+func (m *Map[K, V]) IntersectSorted(keys []K, f func(K, V) bool) {
+	if len(keys) == 0 {
+		return
+	}
+	i := 0
+	m.Range(keys[0], func(key K, value V) bool {
+		for i < len(keys) && m.cmp(keys[i], key) < 0 {
+			i++
+		}
+		if i == len(keys) {
+			return false
+		}
+		if m.cmp(keys[i], key) == 0 {
+			i++
+			return f(key, value)
+		}
+		return true
+	})
+}
+
+// MemoryUsage returns an estimate of the memory footprint of the map, in bytes.
+//
+// The estimate accounts for the map itself and the nodes holding its entries,
+// but not for memory referenced by the keys and values (e.g. the bytes of
+// string keys). Nodes shared with snapshots are accounted for in each map.
+//
+// Complexity: O(1)
+func (m *Map[K, V]) MemoryUsage() int64 {
+	return int64(unsafe.Sizeof(*m)) + int64(m.len)*int64(unsafe.Sizeof(node[K, V]{}))
+}
+
+// WriteDOT writes a representation of the map's internal tree to w in the
+// Graphviz DOT language. Nodes are labeled with their keys and colored red or
+// black, leaf sentinels are omitted.
+//
+// The output is intended for debugging, for example to visualize the effect
+// of a comparison function on the shape of the tree.
+//
+// Complexity: O(n)
+func (m *Map[K, V]) WriteDOT(w io.Writer) error {
+	b := bufio.NewWriter(w)
+	b.WriteString("digraph {\n")
+	b.WriteString("\tnode [style=filled, fontcolor=white];\n")
+	if m.root != nil {
+		id := 0
+		m.writeDOT(b, m.root, &id)
+	}
+	b.WriteString("}\n")
+	return b.Flush()
+}
+
+func (m *Map[K, V]) writeDOT(b *bufio.Writer, n *node[K, V], id *int) int {
+	if n == m.leaf {
+		return -1
+	}
+	self := *id
+	*id++
+
+	color := "black"
+	if n.color == red {
+		color = "red"
+	}
+	fmt.Fprintf(b, "\tn%d [label=%s, fillcolor=%s];\n", self, strconv.Quote(fmt.Sprint(n.key)), color)
+
+	for _, child := range [2]*node[K, V]{n.a, n.b} {
+		if c := m.writeDOT(b, child, id); c >= 0 {
+			fmt.Fprintf(b, "\tn%d -> n%d;\n", self, c)
+		}
+	}
+	return self
+}
+
+// SelectInRange returns the i-th smallest entry (starting at zero) among the
+// entries with keys in the closed interval [lo, hi]. The method returns
+// found=false if i is negative or there are fewer than i+1 entries in the
+// interval.
+//
+// Complexity: O(log n)
+func (m *Map[K, V]) SelectInRange(lo, hi K, i int) (key K, value V, found bool) {
+	if i < 0 || m.root == nil {
+		return key, value, false
+	}
+	rank, _ := m.Rank(lo)
+	if key, value, found = m.Select(rank + i); found && m.cmp(key, hi) > 0 {
+		var zeroKey K
+		var zeroValue V
+		return zeroKey, zeroValue, false
+	}
+	return key, value, found
+}
+
+// Keys returns the keys of the map in ascending order.
+//
+// Complexity: O(n)
+func (m *Map[K, V]) Keys() []K {
+	keys := make([]K, 0, m.len)
+	if m.root != nil {
+		m.rangeFrom(m.root, func(key K, _ V) bool {
+			keys = append(keys, key)
+			return true
+		})
+	}
+	return keys
+}
+
+// Values returns the values of the map in ascending order of their keys.
+//
+// Complexity: O(n)
+func (m *Map[K, V]) Values() []V {
+	values := make([]V, 0, m.len)
+	if m.root != nil {
+		m.rangeFrom(m.root, func(_ K, value V) bool {
+			values = append(values, value)
+			return true
+		})
+	}
+	return values
+}
+
+// ValuesInRange returns the values of the entries with keys in the closed
+// interval [lo, hi], in ascending key order. The returned slice is allocated
+// with the exact number of keys in the interval, which is computed from the
+// ranks of the bounds before collecting the values.
+//
+// Complexity: O(log n) + O(k) with k being the number of keys in the interval
+func (m *Map[K, V]) ValuesInRange(lo, hi K) []V {
+	count := m.CountBetween(lo, hi)
+	if count == 0 {
+		return nil
+	}
+	values := make([]V, 0, count)
+	m.Range(lo, func(_ K, value V) bool {
+		values = append(values, value)
+		return len(values) < count
+	})
+	return values
+}
+
+// Page returns up to limit entries with keys strictly greater than after, in
+// ascending key order. This is intended to implement paginated listings: next
+// is the key of the last entry returned, which should be passed as after to
+// retrieve the following page, and more is true if entries exist past the
+// returned page. If no entries are returned, next is equal to after.
+//
+// Use FirstPage to retrieve the first page of entries.
+//
+// Complexity: O(log n) + O(limit)
+func (m *Map[K, V]) Page(after K, limit int) (entries []Entry[K, V], next K, more bool) {
+	return m.page(after, limit, func(f func(K, V) bool) {
+		m.Range(after, func(key K, value V) bool {
+			return m.cmp(key, after) == 0 || f(key, value)
+		})
+	})
+}
+
+// FirstPage is like Page but returns the entries with the smallest keys of the
+// map.
+//
+// Complexity: O(log n) + O(limit)
+func (m *Map[K, V]) FirstPage(limit int) (entries []Entry[K, V], next K, more bool) {
+	var zero K
+	return m.page(zero, limit, func(f func(K, V) bool) {
+		if m.root != nil {
+			m.rangeFrom(m.root, f)
+		}
+	})
+}
+
+func (m *Map[K, V]) page(after K, limit int, scan func(func(K, V) bool)) (entries []Entry[K, V], next K, more bool) {
+	next = after
+	if limit <= 0 || m.len == 0 {
+		return entries, next, false
+	}
+	scan(func(key K, value V) bool {
+		if len(entries) == limit {
+			more = true
+			return false
+		}
+		entries = append(entries, Entry[K, V]{Key: key, Value: value})
+		return true
+	})
+	if len(entries) != 0 {
+		next = entries[len(entries)-1].Key
+	}
+	return entries, next, more
+}
+
+// RangeSummary returns the number of entries with keys in the closed interval
+// [lo, hi], and the smallest and largest keys of those entries. The method
+// returns found=false if there are no entries in the interval.
+//
+// Complexity: O(log n) + O(k) with k being the number of keys in the interval
+func (m *Map[K, V]) RangeSummary(lo, hi K) (count int, minKey, maxKey K, found bool) {
+	if m.cmp == nil || m.cmp(lo, hi) > 0 {
+		return count, minKey, maxKey, found
+	}
+	m.Range(lo, func(key K, _ V) bool {
+		if m.cmp(key, hi) > 0 {
+			return false
+		}
+		if count == 0 {
+			minKey = key
+		}
+		maxKey = key
+		count++
+		return true
+	})
+	return count, minKey, maxKey, count != 0
+}
+
+// CountGroups returns the number of distinct groups of keys in the map, where
+// keys belong to the same group if the group comparison function reports them
+// as equal (e.g. comparing only the date part of timestamps). The group
+// function must be consistent with the ordering of the map so that keys of a
+// group are adjacent.
+//
+// Complexity: O(n)
+func (m *Map[K, V]) CountGroups(group func(a, b K) int) int {
+	if m.root == nil {
+		return 0
+	}
+	count := 0
+	var prev K
+	m.rangeFrom(m.root, func(key K, _ V) bool {
+		if count == 0 || group(prev, key) != 0 {
+			count++
+		}
+		prev = key
+		return true
+	})
+	return count
+}
+
+// Rank returns the number of keys less than key in the map, which is the
+// position of key in the sorted order of the map (starting at zero) if it
+// exists. The found result indicates whether the key exists in the map.
+//
+// Complexity: O(log n)
+func (m *Map[K, V]) Rank(key K) (rank int, found bool) {
+	if n := m.root; n != nil {
+		for n != m.leaf {
+			switch cmp := m.cmp(key, n.key); {
+			case cmp < 0:
+				n = n.a
+			case cmp > 0:
+				rank += n.a.size + 1
+				n = n.b
+			default:
+				return rank + n.a.size, true
+			}
+		}
+	}
+	return rank, false
+}
+
+// CountBetween returns the number of keys of the map in the closed interval
+// [lo, hi]. The bounds do not need to exist in the map. The interval is empty
+// if lo is greater than hi.
+//
+// Complexity: O(log n)
+func (m *Map[K, V]) CountBetween(lo, hi K) int {
+	if m.root == nil || m.cmp(lo, hi) > 0 {
+		return 0
+	}
+	below, _ := m.Rank(lo)
+	upper, found := m.Rank(hi)
+	if found {
+		upper++
+	}
+	return upper - below
+}
+
+// Select returns the i-th smallest entry of the map, starting at zero. The
+// found result is false if i is out of bounds.
+//
+// Complexity: O(log n)
+func (m *Map[K, V]) Select(i int) (key K, value V, found bool) {
+	if i < 0 || i >= m.len {
+		return key, value, false
+	}
+	for n := m.root; n != m.leaf; {
+		switch {
+		case i < n.a.size:
+			n = n.a
+		case i > n.a.size:
+			i -= n.a.size + 1
+			n = n.b
+		default:
+			return n.key, n.value, true
+		}
+	}
+	return key, value, false
+}
+
+// IsDense returns true if every key of the closed interval [lo, hi] exists in
+// the map, where the keys of the interval are enumerated by the next function
+// returning the successor of its argument (e.g. k+1 for integer keys). The
+// iteration stops at the first missing key. An empty interval (lo greater than
+// hi) is considered dense.
+//
+// Complexity: O(log n) + O(k) with k being the number of keys in the interval
+func (m *Map[K, V]) IsDense(lo, hi K, next func(K) K) (dense bool) {
+	if m.cmp == nil || m.cmp(lo, hi) > 0 {
+		return true
+	}
+	expect := lo
+	m.Range(lo, func(key K, _ V) bool {
+		if m.cmp(key, expect) != 0 {
+			return false
+		}
+		if m.cmp(key, hi) >= 0 {
+			dense = true
+			return false
+		}
+		expect = next(expect)
+		return true
+	})
+	return dense
+}
+
+// quickValidatePaths is the number of root-to-leaf paths sampled by
+// QuickValidate.
+const quickValidatePaths = 8
+
+// Height returns the number of nodes on the longest path from the root of the
+// tree to a leaf, which is zero for an empty map. The height of a red-black
+// tree holding n entries is at most 2*log2(n+1).
+//
+// The method is intended to diagnose the balance of the tree, for example in
+// benchmarks.
+//
+// Complexity: O(n)
+func (m *Map[K, V]) Height() int {
+	if m.root == nil {
+		return 0
+	}
+	var height func(*node[K, V]) int
+	height = func(n *node[K, V]) int {
+		if n == m.leaf {
+			return 0
+		}
+		a, b := height(n.a), height(n.b)
+		if a > b {
+			return a + 1
+		}
+		return b + 1
+	}
+	return height(m.root)
+}
+
+// BlackHeight returns the number of black nodes on the paths from the root of
+// the tree to the leaves, which is the same for all paths and zero for an
+// empty map.
+//
+// Complexity: O(log n)
+func (m *Map[K, V]) BlackHeight() int {
+	if m.root == nil {
+		return 0
+	}
+	return m.blackHeight(m.root)
+}
+
+func (m *Map[K, V]) blackHeight(n *node[K, V]) int {
+	blacks := 0
+	for ; n != m.leaf; n = n.a {
+		if n.color == black {
+			blacks++
+		}
+	}
+	return blacks
+}
+
+// QuickValidate performs a probabilistic check of the integrity of the map,
+// returning an error describing the first inconsistency found.
+//
+// Instead of verifying the invariants of the whole tree, the method samples a
+// few random root-to-leaf paths and checks that the root is black, that red
+// nodes have no red children, that keys are ordered along the paths, that the
+// paths have the same number of black nodes, and that their lengths are
+// consistent with the number of entries reported by Len. It is cheap enough to
+// be run frequently, for example as a periodic probe in long running programs,
+// but may not detect corruptions located outside of the sampled paths.
+//
+// Complexity: O(log n)
+func (m *Map[K, V]) QuickValidate() error {
+	if m.root == nil || m.root == m.leaf || m.root == m.bbleaf {
+		if m.len != 0 {
+			return fmt.Errorf("tree: empty tree with length %d", m.len)
+		}
+		return nil
+	}
+	if m.root.color != black {
+		return errors.New("tree: root is not black")
+	}
+
+	// The height of a red-black tree with n nodes is at most 2*log2(n+1), and
+	// a tree with black height h holds at least 2^h-1 nodes.
+	maxDepth := 2 * bits.Len(uint(m.len+1))
+	blackHeight := -1
+
+	for i := 0; i < quickValidatePaths; i++ {
+		var lo, hi *node[K, V]
+		depth, blacks := 0, 0
+		path := rand.Uint64()
+
+		for n := m.root; n != m.leaf; depth++ {
+			switch {
+			case depth > maxDepth:
+				return fmt.Errorf("tree: path longer than %d nodes for length %d", maxDepth, m.len)
+			case n == nil || n == m.bbleaf:
+				return errors.New("tree: invalid node reference")
+			case n.color != red && n.color != black:
+				return fmt.Errorf("tree: invalid node color %d", n.color)
+			case n.color == red && (n.a.color == red || n.b.color == red):
+				return errors.New("tree: red node has a red child")
+			case lo != nil && m.cmp(lo.key, n.key) >= 0, hi != nil && m.cmp(n.key, hi.key) >= 0:
+				return errors.New("tree: keys are out of order")
+			}
+			if n.color == black {
+				blacks++
+			}
+			if path&1 == 0 {
+				hi, n = n, n.a
+			} else {
+				lo, n = n, n.b
+			}
+			path >>= 1
+		}
+
+		switch {
+		case blackHeight < 0:
+			blackHeight = blacks
+		case blackHeight != blacks:
+			return fmt.Errorf("tree: paths have different black heights: %d and %d", blackHeight, blacks)
+		}
+	}
+
+	if h := uint(blackHeight); h < bits.UintSize && (1<<h)-1 > uint(m.len) {
+		return fmt.Errorf("tree: black height %d is too large for length %d", blackHeight, m.len)
+	}
+	return nil
+}
+
+// Reduce folds the entries of m in ascending key order, calling f with the
+// accumulated value and each entry, and returning the final accumulator.
+//
+// Reduce is a function rather than a method because Go methods cannot declare
+// their own type parameters.
+//
+// Complexity: O(n)
+func Reduce[K, V, A any](m *Map[K, V], init A, f func(acc A, key K, value V) A) A {
+	acc := init
+	if m.root != nil {
+		m.rangeFrom(m.root, func(key K, value V) bool {
+			acc = f(acc, key, value)
+			return true
+		})
+	}
+	return acc
+}
+
+// BuildSorted replaces the content of the map with the entries passed as
+// argument, which must be sorted in strictly ascending key order according to
+// the comparison function of the map. The method panics if the entries are not
+// sorted or if the map was not initialized.
+//
+// The tree is built directly from the sorted entries, which is faster than
+// inserting them one by one when loading large data sets.
+//
+// Complexity: O(n)
+func (m *Map[K, V]) BuildSorted(entries []Entry[K, V]) {
+	if m.cmp == nil {
+		panic("tree: BuildSorted called on a map which was not initialized")
+	}
+	for i := 1; i < len(entries); i++ {
+		if m.cmp(entries[i-1].Key, entries[i].Key) >= 0 {
+			panic(fmt.Sprintf("tree: BuildSorted entries are not in strictly ascending key order: %v before %v", entries[i-1].Key, entries[i].Key))
+		}
+	}
+	m.build(len(entries), func(i int) (K, V) { return entries[i].Key, entries[i].Value })
+}
+
+// build replaces the content of the map with n entries returned by the entry
+// function, which must produce keys in strictly ascending order.
+func (m *Map[K, V]) build(n int, entry func(int) (K, V)) {
+	m.version++
+	nodes := make([]*node[K, V], n)
+	for i := range nodes {
+		key, value := entry(i)
+		nodes[i] = &node[K, V]{key: key, value: value, version: m.version, epoch: m.epoch}
+	}
+	m.link(nodes)
+}
+
+// link replaces the content of the map with the nodes passed as argument, which
+// must be sorted in strictly ascending key order.
+//
+// The tree is built balanced in a single pass: the nodes on the deepest level
+// are colored red and all the others black, which satisfies the red-black tree
+// invariants since the depth of leaves differs by at most one.
+func (m *Map[K, V]) link(nodes []*node[K, V]) {
+	depth := bits.Len(uint(len(nodes))) - 1
+
+	var link func(nodes []*node[K, V], d int) *node[K, V]
+	link = func(nodes []*node[K, V], d int) *node[K, V] {
+		if len(nodes) == 0 {
+			return m.leaf
+		}
+		mid := len(nodes) / 2
+		n := nodes[mid]
+		n.color = black
+		if d == depth {
+			n.color = red
+		}
+		n.a = link(nodes[:mid], d+1)
+		n.b = link(nodes[mid+1:], d+1)
+		return update(n)
+	}
+
+	m.root = m.blacken(link(nodes, 0))
+	m.len = len(nodes)
+}
+
+// PopRange removes the entries with keys in the closed interval [lo, hi] from
+// the map, and returns them in ascending key order.
+//
+// When the removed entries make up a large fraction of the map, the tree is
+// rebuilt from the remaining entries instead of deleting entries one by one.
+//
+// Complexity: O(log n) + O(k log n) with k being the number of entries
+// removed, or O(n) when k is greater than n/2
+func (m *Map[K, V]) PopRange(lo, hi K) []Entry[K, V] {
+	var entries []Entry[K, V]
+	if m.cmp == nil || m.cmp(lo, hi) > 0 {
+		return entries
+	}
+
+	m.Range(lo, func(key K, value V) bool {
+		if m.cmp(key, hi) > 0 {
+			return false
+		}
+		entries = append(entries, Entry[K, V]{Key: key, Value: value})
+		return true
+	})
+
+	if 2*len(entries) <= m.len {
+		for _, e := range entries {
+			m.Delete(e.Key)
+		}
+		return entries
+	}
+
+	nodes := make([]*node[K, V], 0, m.len-len(entries))
+	m.rangeNodes(m.root, func(n *node[K, V]) {
+		if m.cmp(n.key, lo) < 0 || m.cmp(n.key, hi) > 0 {
+			nodes = append(nodes, m.own(n))
+		}
+	})
+	m.link(nodes)
+	return entries
+}
+
+// TrimBelow deletes all entries with keys less than key from the map, and
+// returns the number of entries deleted.
+//
+// Like PopRange, the tree is rebuilt from the remaining entries when the
+// deleted entries make up a large fraction of the map.
+//
+// Complexity: O(k log n) with k being the number of entries deleted, or O(n)
+// when k is greater than n/2
+func (m *Map[K, V]) TrimBelow(key K) int {
+	if m.root == nil {
+		return 0
+	}
+	var keys []K
+	m.rangeFrom(m.root, func(k K, _ V) bool {
+		if m.cmp(k, key) >= 0 {
+			return false
+		}
+		keys = append(keys, k)
+		return true
+	})
+	return m.trim(keys, func(k K) bool { return m.cmp(k, key) >= 0 })
+}
+
+// TrimAbove deletes all entries with keys greater than key from the map, and
+// returns the number of entries deleted.
+//
+// Complexity: O(k log n) with k being the number of entries deleted, or O(n)
+// when k is greater than n/2
+func (m *Map[K, V]) TrimAbove(key K) int {
+	if m.root == nil {
+		return 0
+	}
+	var keys []K
+	m.rangeFromReverse(m.root, func(k K, _ V) bool {
+		if m.cmp(k, key) <= 0 {
+			return false
+		}
+		keys = append(keys, k)
+		return true
+	})
+	return m.trim(keys, func(k K) bool { return m.cmp(k, key) <= 0 })
+}
+
+// DeletePrefix deletes all entries with keys starting with prefix from the map,
+// and returns the number of entries deleted.
+//
+// Since the keys are ordered, the keys starting with the prefix form the
+// contiguous interval [prefix, upperBound(prefix)), where upperBound returns
+// the smallest key greater than all keys starting with the prefix (e.g. the
+// prefix with its last byte incremented for string keys). The hasPrefix
+// function reports whether a key starts with the prefix.
+//
+// Complexity: O(log n) + O(k log n) with k being the number of entries
+// deleted, or O(n) when k is greater than n/2
+func (m *Map[K, V]) DeletePrefix(prefix K, hasPrefix func(k, prefix K) bool, upperBound func(prefix K) K) int {
+	if m.root == nil {
+		return 0
+	}
+	limit := upperBound(prefix)
+	match := func(k K) bool { return m.cmp(k, limit) < 0 && hasPrefix(k, prefix) }
+
+	var keys []K
+	m.Range(prefix, func(k K, _ V) bool {
+		if !match(k) {
+			return false
+		}
+		keys = append(keys, k)
+		return true
+	})
+	return m.trim(keys, func(k K) bool { return m.cmp(k, prefix) < 0 || !match(k) })
+}
+
+// trim deletes the given keys from the map, either one by one or by rebuilding
+// the tree from the nodes with keys matching the keep function.
+func (m *Map[K, V]) trim(keys []K, keep func(K) bool) int {
+	if 2*len(keys) <= m.len {
+		for _, k := range keys {
+			m.Delete(k)
+		}
+		return len(keys)
+	}
+
+	nodes := make([]*node[K, V], 0, m.len-len(keys))
+	m.rangeNodes(m.root, func(n *node[K, V]) {
+		if keep(n.key) {
+			nodes = append(nodes, m.own(n))
+		}
+	})
+	m.link(nodes)
+	return len(keys)
+}
+
+// Cut removes the entries with keys in the closed interval [lo, hi] from the
+// map, and returns them in a new map using the same comparison function.
+//
+// The new map is built from the sorted entries removed by PopRange in a single
+// pass, which does not require rebalancing the tree.
+//
+// Complexity: O(log n) + O(k log n) with k being the number of entries
+// removed, or O(n) when k is greater than n/2
+func (m *Map[K, V]) Cut(lo, hi K) *Map[K, V] {
+	entries := m.PopRange(lo, hi)
+	c := m.empty()
+	c.build(len(entries), func(i int) (K, V) { return entries[i].Key, entries[i].Value })
+	return c
+}
+
+// rangeNodes calls f for each node of the subtree rooted at n, in ascending
+// key order.
+func (m *Map[K, V]) rangeNodes(n *node[K, V], f func(*node[K, V])) {
+	if n != m.leaf {
+		m.rangeNodes(n.a, f)
+		f(n)
+		m.rangeNodes(n.b, f)
+	}
+}
+
+// Split returns two new maps holding the entries of m with keys less than the
+// pivot, and greater or equal to the pivot. The map m is not modified.
+//
+// The new maps share the nodes of m, like snapshots, and are assembled by
+// joining the subtrees on either side of the search path of the pivot, which
+// only allocates the nodes on that path.
+//
+// Complexity: O(log n)
+func (m *Map[K, V]) Split(pivot K) (left, right *Map[K, V]) {
+	left, right = m.empty(), m.empty()
+	if m.root == nil {
+		return left, right
+	}
+	m.epoch = nextEpoch()
+
+	l, _, r, _ := left.split(m.root, m.blackHeight(m.root), pivot)
+	left.version, left.root, left.len = m.version, left.blacken(l), l.size
+	right.version, right.root, right.len = m.version, right.blacken(r), r.size
+	return left, right
+}
+
+// split partitions the subtree rooted at n, of black height h, into the trees
+// holding the keys less than the pivot, and greater or equal to the pivot,
+// returned with their black heights. The roots of the trees may be red.
+func (m *Map[K, V]) split(n *node[K, V], h int, pivot K) (l *node[K, V], hl int, r *node[K, V], hr int) {
+	if n == m.leaf {
+		return m.leaf, 0, m.leaf, 0
+	}
+	if n.color == black {
+		h--
+	}
+	switch cmp := m.cmp(pivot, n.key); {
+	case cmp < 0:
+		l, hl, r, hr = m.split(n.a, h, pivot)
+		r, hr = m.join(r, hr, n, n.b, h)
+	case cmp > 0:
+		l, hl, r, hr = m.split(n.b, h, pivot)
+		l, hl = m.join(n.a, h, n, l, hl)
+	default:
+		l, hl = n.a, h
+		r, hr = m.join(m.leaf, 0, n, n.b, h)
+	}
+	return l, hl, r, hr
+}
+
+// join returns the root and black height of a tree holding the nodes of l, k
+// and r, where l and r have black heights hl and hr, the keys of l are less
+// than the key of k, and the keys of r are greater. Nodes are copied before
+// being modified if they are not owned by m.
+//
+// The node k is placed on the spine of the taller tree at the level where its
+// black height matches the one of the shorter tree, then the tree is
+// rebalanced up to the root like after an insertion.
+//
+// Complexity: O(|hl - hr| + 1)
+func (m *Map[K, V]) join(l *node[K, V], hl int, k, r *node[K, V], hr int) (*node[K, V], int) {
+	if l.color == red {
+		l, hl = m.blacken(l), hl+1
+	}
+	if r.color == red {
+		r, hr = m.blacken(r), hr+1
+	}
+	k = m.own(k)
+
+	var n *node[K, V]
+	var h int
+	switch {
+	case hl > hr:
+		n, h = m.joinRight(l, hl, k, r, hr), hl
+	case hl < hr:
+		n, h = m.joinLeft(l, hl, k, r, hr), hr
+	default:
+		k.a, k.b, k.color = l, r, red
+		return update(k), hl
+	}
+	if n.color == red {
+		n, h = m.blacken(n), h+1
+	}
+	return n, h
+}
+
+func (m *Map[K, V]) joinRight(n *node[K, V], h int, k, r *node[K, V], hr int) *node[K, V] {
+	if n.color == black {
+		if h == hr {
+			k.a, k.b, k.color = n, r, red
+			return update(k)
+		}
+		h--
+	}
+	n = m.own(n)
+	n.b = m.joinRight(n.b, h, k, r, hr)
+	return m.balance(n)
+}
+
+func (m *Map[K, V]) joinLeft(l *node[K, V], hl int, k, n *node[K, V], h int) *node[K, V] {
+	if n.color == black {
+		if h == hl {
+			k.a, k.b, k.color = l, n, red
+			return update(k)
+		}
+		h--
+	}
+	n = m.own(n)
+	n.a = m.joinLeft(l, hl, k, n.a, h)
+	return m.balance(n)
+}
+
+// Join returns a new map holding the entries of left and right, which must have
+// disjoint key ranges: every key of left must be less than every key of right,
+// otherwise Join panics. The left and right maps are not modified.
+//
+// The new map uses the comparison function of left, unless left was not
+// initialized. Like Split, the new map shares the nodes of left and right: the
+// smallest entry of right is removed and placed between the two trees at the
+// level where their black heights match, which only copies the nodes on the
+// paths leading to it.
+//
+// Nodes can only be shared by maps derived from one another (e.g. by Split or
+// Snapshot), the entries of the smallest of two unrelated maps are copied
+// first.
+//
+// Complexity: O(log n + log m) for related maps, O(min(n, m)) otherwise
+func Join[K, V any](left, right *Map[K, V]) *Map[K, V] {
+	base := left
+	if base.cmp == nil {
+		base = right
+	}
+	if maxKey, _, ok := left.Max(); ok {
+		if minKey, _, ok := right.Min(); ok && base.cmp(maxKey, minKey) >= 0 {
+			panic(fmt.Sprintf("tree: Join called on maps with overlapping key ranges: %v is not less than %v", maxKey, minKey))
+		}
+	}
+
+	m := base.empty()
+	larger := left
+	if right.len > left.len || left.leaf == nil {
+		larger = right
+	}
+	if larger.leaf != nil {
+		m.leaf, m.bbleaf, m.root = larger.leaf, larger.bbleaf, larger.leaf
+	}
+	m.version = left.version
+	if right.version > m.version {
+		m.version = right.version
+	}
+	m.len = left.len + right.len
+
+	l, r := m.adopt(left), m.adopt(right)
+	switch {
+	case l == m.leaf:
+		m.root = r
+	case r == m.leaf:
+		m.root = l
+	default:
+		k := min(r, m.leaf)
+		if r = m.removeMin(r); r == m.bbleaf {
+			r = m.leaf
+		}
+		r = m.blacken(r)
+		n, _ := m.join(l, m.blackHeight(l), k, r, m.blackHeight(r))
+		m.root = m.blacken(n)
+	}
+	return m
 }
 
-// NewMap instantiates a new map using the given comparison function to order
-// the keys.
-func NewMap[K, V any](cmp func(K, K) int) *Map[K, V] {
-	m := new(Map[K, V])
-	m.Init(cmp)
-	return m
+// adopt returns the root of the tree of other, which m may link into its own
+// tree. The tree is shared if the maps have the same leaf sentinels, in which
+// case other is assigned a new epoch to stop modifying the shared nodes in
+// place, or copied otherwise.
+func (m *Map[K, V]) adopt(other *Map[K, V]) *node[K, V] {
+	switch {
+	case other.len == 0:
+		return m.leaf
+	case other.leaf == m.leaf:
+		other.epoch = nextEpoch()
+		return other.root
+	default:
+		return m.clone(other.root, other.leaf)
+	}
 }
 
-// Init initializes (or re-initializes) the map. The comparison function passed
-// as argument will be used to order the keys.
+// Clone returns a copy of the map. The copy has the same configuration and
+// shape as m, but its nodes, including the leaf sentinels, are allocated
+// independently, so modifications of either map are not visible in the other.
 //
-// Init must be called prior to inserting keys in the map, otherwise inserts
-// will panic.
-//
-// Complexity: O(1)
-func (m *Map[K, V]) Init(cmp func(K, K) int) {
-	m.leaf = node[K, V]{color: black}
-	m.leaf.a = &m.leaf
-	m.leaf.b = &m.leaf
-	m.bbleaf = node[K, V]{color: bblack}
-	m.bbleaf.a = &m.leaf
-	m.bbleaf.b = &m.leaf
-	m.cmp = cmp
-	m.len = 0
-	m.root = &m.leaf
+// Complexity: O(n)
+func (m *Map[K, V]) Clone() *Map[K, V] {
+	c := &Map[K, V]{}
+	if m.root != nil {
+		c.InitStrict(m.cmp, m.equal)
+		c.len, c.version = m.len, m.version
+		c.root = c.clone(m.root, m.leaf)
+	}
+	return c
 }
 
-// Len returns the number of entries currently held in the map.
+// Snapshot returns a copy of the map as it is at the time of the call. The
+// snapshot shares the nodes of m instead of copying them: the next writes to
+// either map copy the nodes on the paths they modify, leaving the other map
+// unchanged. Holding a snapshot therefore costs nothing until the map is
+// modified, then O(log n) memory per write at most.
 //
-// Complexity: O(1)
-func (m *Map[K, V]) Len() int { return m.len }
-
-// Range calls f for each entry of the map for each key greater or equal to the
-// min key passed as first argument. The keys and values are presented in
-// ascending order according to the comparison function installed on the map.
+// The snapshot may also be modified without affecting m, and snapshots may be
+// taken of snapshots. A snapshot may be read concurrently with modifications
+// of m, but each map must not be read and modified concurrently.
 //
-// Complexity: O(log n) + O(k) with k being the number of calls to f
-func (m *Map[K, V]) Range(min K, f func(K, V) bool) {
+// Complexity: O(1)
+func (m *Map[K, V]) Snapshot() *Map[K, V] {
+	s := *m
 	if m.root != nil {
-		m.findAndRange(m.root, min, f)
+		s.epoch, m.epoch = nextEpoch(), nextEpoch()
 	}
+	return &s
 }
 
-func (m *Map[K, V]) findAndRange(n *node[K, V], key K, f func(K, V) bool) bool {
-	if n == &m.leaf {
-		return true
+// clone copies the subtree rooted at n, which belongs to a map with the given
+// leaf, replacing the references to the leaf with the leaf of m.
+func (m *Map[K, V]) clone(n, leaf *node[K, V]) *node[K, V] {
+	if n == leaf {
+		return m.leaf
 	}
-	switch cmp := m.cmp(key, n.key); {
-	case cmp < 0:
-		return m.findAndRange(n.a, key, f) && f(n.key, n.value) && m.rangeFrom(n.b, f)
-	case cmp > 0:
-		return m.findAndRange(n.b, key, f)
-	default:
-		return f(n.key, n.value) && m.rangeFrom(n.b, f)
+	dup := *n
+	dup.epoch = m.epoch
+	dup.a = m.clone(n.a, leaf)
+	dup.b = m.clone(n.b, leaf)
+	return &dup
+}
+
+// Equal returns true if m and other hold the same keys, associated with values
+// that the eq function reports as equal. Keys are compared with the comparison
+// function of m, the maps may have different shapes.
+//
+// Complexity: O(n)
+func (m *Map[K, V]) Equal(other *Map[K, V], eq func(a, b V) bool) bool {
+	if m.len != other.len {
+		return false
 	}
+	if m.len == 0 {
+		return true
+	}
+	c := &cursor[K, V]{m: other}
+	c.init()
+	return m.rangeFrom(m.root, func(key K, value V) bool {
+		equal := c.node != nil && m.cmp(key, c.node.key) == 0 && eq(value, c.node.value)
+		c.next()
+		return equal
+	})
 }
 
-func (m *Map[K, V]) rangeFrom(n *node[K, V], call func(K, V) bool) bool {
-	return n == &m.leaf || (m.rangeFrom(n.a, call) && call(n.key, n.value) && m.rangeFrom(n.b, call))
+// empty returns a new empty map with the same configuration as m.
+// The new map shares the leaf sentinels of m, which allows the maps to exchange
+// nodes.
+func (m *Map[K, V]) empty() *Map[K, V] {
+	e := NewMap[K, V](m.cmp)
+	e.equal = m.equal
+	if m.leaf != nil {
+		e.leaf, e.bbleaf, e.root = m.leaf, m.bbleaf, m.leaf
+	}
+	return e
 }
 
 // Insert inserts a new entry in the map, or replaces the value if the key
@@ -128,49 +1251,190 @@ func (m *Map[K, V]) rangeFrom(n *node[K, V], call func(K, V) bool) bool {
 // whether the value was replaced.
 //
 // The map must have been initialized by a call to NewMap or Init or the call
-// to Insert will panic.
+// to Insert will panic. On maps initialized with NewMapStrict or InitStrict,
+// Insert also panics if the key compares equal to an existing key which is
+// not identical according to the equal function.
 //
 // Complexity: O(log n)
 func (m *Map[K, V]) Insert(key K, value V) (previous V, replaced bool) {
-	inserted, previous, replaced := m.insert(m.root, key, value)
-	m.root = blacken(inserted)
+	m.mustBeInitialized("Insert")
+	m.version++
+	inserted, _, previous, replaced := m.insert(m.root, key, value, true)
+	m.root = m.blacken(inserted)
 	if !replaced {
 		m.len++
 	}
 	return previous, replaced
 }
 
-func (m *Map[K, V]) insert(n *node[K, V], key K, value V) (inserted *node[K, V], previous V, replaced bool) {
-	if n == &m.leaf {
+// InsertStrict is like Insert but returns ErrKeyMismatch instead of panicking
+// when the map was initialized with NewMapStrict or InitStrict and the key
+// compares equal to an existing key which is not identical according to the
+// equal function. The map is not modified when an error is returned.
+//
+// On maps which are not strict, InsertStrict behaves like Insert and never
+// returns an error.
+//
+// Complexity: O(log n)
+func (m *Map[K, V]) InsertStrict(key K, value V) (previous V, replaced bool, err error) {
+	m.mustBeInitialized("InsertStrict")
+	if m.equal != nil {
+		if n := m.lookup(key); n != nil && !m.equal(key, n.key) {
+			return previous, false, fmt.Errorf("%w: %v and %v", ErrKeyMismatch, key, n.key)
+		}
+	}
+	previous, replaced = m.Insert(key, value)
+	return previous, replaced, nil
+}
+
+// LookupOrInsert returns the value associated with key if it exists in the
+// map, otherwise it inserts the key with the value passed as argument and
+// returns it. The loaded result is true if the key existed in the map.
+//
+// The key is located in a single descent of the tree, which makes
+// LookupOrInsert cheaper than a Lookup followed by an Insert.
+//
+// Complexity: O(log n)
+func (m *Map[K, V]) LookupOrInsert(key K, value V) (actual V, loaded bool) {
+	m.mustBeInitialized("LookupOrInsert")
+	m.version++
+	inserted, entry, _, loaded := m.insert(m.root, key, value, false)
+	m.root = m.blacken(inserted)
+	if !loaded {
+		m.len++
+	}
+	return entry.value, loaded
+}
+
+// InsertWith sets the value associated with key to the result of calling f
+// with the current value and true if the key existed, or the zero-value and
+// false otherwise. The method returns the new value.
+//
+// The key is located in a single descent of the tree, which makes InsertWith
+// cheaper than a Lookup followed by an Insert. The function must not modify
+// the map.
+//
+// Complexity: O(log n)
+func (m *Map[K, V]) InsertWith(key K, f func(old V, existed bool) V) V {
+	m.mustBeInitialized("InsertWith")
+	var zero V
+	m.version++
+	inserted, entry, previous, replaced := m.insert(m.root, key, zero, true)
+	m.root = m.blacken(inserted)
+	if !replaced {
+		m.len++
+	}
+	entry.value = f(previous, replaced)
+	return entry.value
+}
+
+// Merge inserts every entry of other in m. When a key exists in both maps,
+// resolve is called with the key, the value in m, and the value in other, and
+// returns the value retained in m. If resolve is nil, the value from other is
+// retained. The other map is not modified.
+//
+// Complexity: O(k log n) with k being the number of entries in other
+func (m *Map[K, V]) Merge(other *Map[K, V], resolve func(key K, a, b V) V) {
+	m.mustBeInitialized("Merge")
+	if other.root == nil {
+		return
+	}
+	other.rangeFrom(other.root, func(key K, value V) bool {
+		m.version++
+		inserted, entry, previous, replaced := m.insert(m.root, key, value, true)
+		m.root = m.blacken(inserted)
+		if !replaced {
+			m.len++
+		} else if resolve != nil {
+			entry.value = resolve(key, previous, value)
+		}
+		return true
+	})
+}
+
+// Number is a type constraint enumerating the numeric types supported by
+// Increment.
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 | ~uint | ~uintptr | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~float32 | ~float64
+}
+
+// Increment adds delta to the value associated with key in m, inserting the key
+// with a value of delta if it did not exist, and returns the new value.
+//
+// The key is located in a single descent of the tree, which makes Increment
+// cheaper than a Lookup followed by an Insert. Increment is a function rather
+// than a method because the value type must be numeric.
+//
+// Complexity: O(log n)
+func Increment[K any, V Number](m *Map[K, V], key K, delta V) V {
+	m.mustBeInitialized("Increment")
+	m.version++
+	inserted, entry, previous, replaced := m.insert(m.root, key, delta, true)
+	m.root = m.blacken(inserted)
+	if replaced {
+		// The version of the entry and its ancestors was updated when the
+		// value was replaced, only the value needs to be adjusted.
+		entry.value = previous + delta
+	} else {
+		m.len++
+	}
+	return entry.value
+}
+
+// mustBeInitialized panics if the map was not initialized. Inserting keys in a
+// map without leaf sentinels would create nodes that the next reads of the map
+// could not tell apart from the leaves.
+func (m *Map[K, V]) mustBeInitialized(method string) {
+	if m.leaf == nil {
+		panic("tree: " + method + " called on a map which was not initialized")
+	}
+}
+
+// insert inserts the key in the subtree rooted at n, returning the new root of
+// the subtree and the node holding the key. If the key already exists, its
+// value is only replaced if replace is true.
+func (m *Map[K, V]) insert(n *node[K, V], key K, value V, replace bool) (inserted, entry *node[K, V], previous V, replaced bool) {
+	if n == m.leaf {
 		inserted = &node[K, V]{
-			a:     &m.leaf,
-			b:     &m.leaf,
-			key:   key,
-			value: value,
-			color: red,
+			a:          m.leaf,
+			b:          m.leaf,
+			key:        key,
+			value:      value,
+			version:    m.version,
+			maxVersion: m.version,
+			size:       1,
+			epoch:      m.epoch,
+			color:      red,
 		}
+		entry = inserted
 	} else {
+		n = m.own(n)
 		switch cmp := m.cmp(key, n.key); {
 		case cmp < 0:
-			n.a, previous, replaced = m.insert(n.a, key, value)
-			inserted = balance(n)
+			n.a, entry, previous, replaced = m.insert(n.a, key, value, replace)
+			inserted = m.balance(n)
 		case cmp > 0:
-			n.b, previous, replaced = m.insert(n.b, key, value)
-			inserted = balance(n)
+			n.b, entry, previous, replaced = m.insert(n.b, key, value, replace)
+			inserted = m.balance(n)
 		default:
-			inserted, previous, replaced = n, n.value, true
-			n.value = value
+			if m.equal != nil && !m.equal(key, n.key) {
+				panic(fmt.Sprintf("tree: comparison function reports distinct keys as equal: %v and %v", key, n.key))
+			}
+			inserted, entry, previous, replaced = n, n, n.value, true
+			if replace {
+				n.value, n.version, n.maxVersion = value, m.version, m.version
+			}
 		}
 	}
-	return inserted, previous, replaced
+	return inserted, entry, previous, replaced
 }
 
 // Min returns the entry with the smallest key in the map.
 //
 // Complexity: O(log n)
 func (m *Map[K, V]) Min() (key K, value V, found bool) {
-	if m.root != nil {
-		n := min(m.root, &m.leaf)
+	if m.root != nil && m.root != m.leaf {
+		n := min(m.root, m.leaf)
 		key, value, found = n.key, n.value, true
 	}
 	return key, value, found
@@ -180,8 +1444,8 @@ func (m *Map[K, V]) Min() (key K, value V, found bool) {
 //
 // Complexity: O(log n)
 func (m *Map[K, V]) Max() (key K, value V, found bool) {
-	if m.root != nil {
-		n := max(m.root, &m.leaf)
+	if m.root != nil && m.root != m.leaf {
+		n := max(m.root, m.leaf)
 		key, value, found = n.key, n.value, true
 	}
 	return key, value, found
@@ -192,19 +1456,158 @@ func (m *Map[K, V]) Max() (key K, value V, found bool) {
 //
 // Complexity: O(log n)
 func (m *Map[K, V]) Lookup(key K) (value V, found bool) {
+	if n := m.lookup(key); n != nil {
+		return n.value, true
+	}
+	return value, false
+}
+
+// LookupOr returns the value associated with the given key in the map, or def
+// if the key was not found.
+//
+// Complexity: O(log n)
+func (m *Map[K, V]) LookupOr(key K, def V) V {
+	if n := m.lookup(key); n != nil {
+		return n.value
+	}
+	return def
+}
+
+// MustLookup returns the value associated with the given key in the map, and
+// panics if the key was not found.
+//
+// Complexity: O(log n)
+func (m *Map[K, V]) MustLookup(key K) V {
+	n := m.lookup(key)
+	if n == nil {
+		panic(fmt.Sprintf("tree: key not found in map: %v", key))
+	}
+	return n.value
+}
+
+func (m *Map[K, V]) lookup(key K) *node[K, V] {
 	if n := m.root; n != nil {
-		for n != &m.leaf {
+		for n != m.leaf {
 			switch cmp := m.cmp(key, n.key); {
 			case cmp < 0:
 				n = n.a
 			case cmp > 0:
 				n = n.b
 			default:
-				return n.value, true
+				return n
 			}
 		}
 	}
-	return value, false
+	return nil
+}
+
+// CompareAndSwap replaces the value associated with key by new, only if the
+// key exists and its current value is equal to old according to the equal
+// function. The method returns true if the value was swapped.
+//
+// Complexity: O(log n)
+func (m *Map[K, V]) CompareAndSwap(key K, old, new V, equal func(V, V) bool) (swapped bool) {
+	if n := m.lookup(key); n != nil && equal(n.value, old) {
+		m.version++
+		n = m.touch(key)
+		n.value, n.version, swapped = new, m.version, true
+	}
+	return swapped
+}
+
+// touch propagates the current version of the map to the nodes on the path
+// from the root to the node matching key, and returns that node so its value
+// can be modified in place. Since the current version is the largest, no
+// comparisons with the children of the nodes are needed.
+func (m *Map[K, V]) touch(key K) *node[K, V] {
+	for p := &m.root; *p != m.leaf; {
+		n := m.own(*p)
+		n.maxVersion = m.version
+		*p = n
+		switch cmp := m.cmp(key, n.key); {
+		case cmp < 0:
+			p = &n.a
+		case cmp > 0:
+			p = &n.b
+		default:
+			return n
+		}
+	}
+	return nil
+}
+
+// Apply replaces the value of each entry of the map with the result of calling
+// f with the key and the current value, in ascending key order. The values are
+// updated in place, the structure of the tree is not modified.
+//
+// All the entries are considered changed by the call to Apply, and will be
+// presented by RangeChangedSince for versions prior to the call.
+//
+// Complexity: O(n)
+func (m *Map[K, V]) Apply(f func(K, V) V) {
+	if m.root == nil || m.root == m.leaf {
+		return
+	}
+	m.version++
+	m.root = m.apply(m.root, f)
+}
+
+func (m *Map[K, V]) apply(n *node[K, V], f func(K, V) V) *node[K, V] {
+	if n == m.leaf {
+		return n
+	}
+	n = m.own(n)
+	n.a = m.apply(n.a, f)
+	n.value = f(n.key, n.value)
+	n.version, n.maxVersion = m.version, m.version
+	n.b = m.apply(n.b, f)
+	return n
+}
+
+// Version returns the current version of the map. The version is incremented
+// each time an entry is inserted or its value is replaced, which allows
+// programs to track changes with RangeChangedSince.
+//
+// Complexity: O(1)
+func (m *Map[K, V]) Version() uint64 { return m.version }
+
+// RangeChangedSince calls f for each entry of the map which was inserted or
+// had its value replaced after the map was at the given version, in ascending
+// key order. If f returns false, the iteration is stopped.
+//
+// Deleted entries are not reported; programs which need to track deletions
+// must do so separately.
+//
+// Complexity: O(k log n) with k being the number of calls to f
+func (m *Map[K, V]) RangeChangedSince(version uint64, f func(K, V) bool) {
+	if m.root != nil {
+		m.rangeChangedSince(m.root, version, f)
+	}
+}
+
+func (m *Map[K, V]) rangeChangedSince(n *node[K, V], version uint64, f func(K, V) bool) bool {
+	if n == m.leaf || n.maxVersion <= version {
+		return true
+	}
+	return m.rangeChangedSince(n.a, version, f) &&
+		(n.version <= version || f(n.key, n.value)) &&
+		m.rangeChangedSince(n.b, version, f)
+}
+
+// CompareAndDelete deletes the entry associated with key, only if the key
+// exists and its current value is equal to old according to the equal
+// function. The method returns true if the entry was deleted.
+//
+// Complexity: O(log n)
+func (m *Map[K, V]) CompareAndDelete(key K, old V, equal func(V, V) bool) (deleted bool) {
+	if m.root != nil {
+		var n *node[K, V]
+		n, _, deleted = m.delete(m.root, key, func(value V) bool { return equal(value, old) })
+		if deleted {
+			m.setRoot(n)
+		}
+	}
+	return deleted
 }
 
 // Search returns the entry found in the map where the key was less or equal to
@@ -215,7 +1618,7 @@ func (m *Map[K, V]) Search(key K) (matchKey K, matchValue V, found bool) {
 	if n := m.root; n != nil {
 		r := (*node[K, V])(nil)
 
-		for n != &m.leaf {
+		for n != m.leaf {
 			switch cmp := m.cmp(key, n.key); {
 			case cmp < 0:
 				n = n.a
@@ -234,33 +1637,165 @@ func (m *Map[K, V]) Search(key K) (matchKey K, matchValue V, found bool) {
 	return matchKey, matchValue, found
 }
 
+// Floor returns the entry of the map with the largest key less or equal to the
+// one passed as argument. It is equivalent to Search.
+//
+// Complexity: O(log n)
+func (m *Map[K, V]) Floor(key K) (matchKey K, matchValue V, found bool) {
+	return m.Search(key)
+}
+
+// Ceiling returns the entry of the map with the smallest key greater or equal
+// to the one passed as argument.
+//
+// Complexity: O(log n)
+func (m *Map[K, V]) Ceiling(key K) (matchKey K, matchValue V, found bool) {
+	if n := m.root; n != nil {
+		r := (*node[K, V])(nil)
+
+		for n != m.leaf {
+			switch cmp := m.cmp(key, n.key); {
+			case cmp < 0:
+				r = n
+				n = n.a
+			case cmp > 0:
+				n = n.b
+			default:
+				return n.key, n.value, true
+			}
+		}
+
+		if r != nil {
+			return r.key, r.value, true
+		}
+	}
+	return matchKey, matchValue, found
+}
+
+// Successor returns the entry of the map with the smallest key strictly greater
+// than the one passed as argument, which does not need to exist in the map.
+//
+// Successor allows programs to step through the entries of the map one at a
+// time, in cases where the callback model of Range does not fit.
+//
+// Complexity: O(log n)
+func (m *Map[K, V]) Successor(key K) (nextKey K, nextValue V, found bool) {
+	if n := m.root; n != nil {
+		r := (*node[K, V])(nil)
+
+		for n != m.leaf {
+			if m.cmp(key, n.key) < 0 {
+				r = n
+				n = n.a
+			} else {
+				n = n.b
+			}
+		}
+
+		if r != nil {
+			return r.key, r.value, true
+		}
+	}
+	return nextKey, nextValue, found
+}
+
+// Predecessor returns the entry of the map with the largest key strictly less
+// than the one passed as argument, which does not need to exist in the map.
+//
+// Complexity: O(log n)
+func (m *Map[K, V]) Predecessor(key K) (prevKey K, prevValue V, found bool) {
+	if n := m.root; n != nil {
+		r := (*node[K, V])(nil)
+
+		for n != m.leaf {
+			if m.cmp(key, n.key) > 0 {
+				r = n
+				n = n.b
+			} else {
+				n = n.a
+			}
+		}
+
+		if r != nil {
+			return r.key, r.value, true
+		}
+	}
+	return prevKey, prevValue, found
+}
+
 // Delete deletes the given key from the map. If the key does not exist,
 // the map is not modified. The method returns the value removed from the map
 // and a boolean indicating whether the key was found.
 //
+// Unlike inserts, deletes are valid on a map which was not initialized, they
+// have no effect since the map is empty.
+//
 // Complexity: O(log n)
 func (m *Map[K, V]) Delete(key K) (value V, deleted bool) {
 	if m.root != nil {
 		var n *node[K, V]
-		n, value, deleted = m.delete(m.root, key)
+		n, value, deleted = m.delete(m.root, key, nil)
 		if deleted {
-			m.root = blacken(n)
+			m.setRoot(n)
 		}
 	}
 	return value, deleted
 }
 
-func (m *Map[K, V]) delete(n *node[K, V], key K) (node *node[K, V], value V, deleted bool) {
-	if n == &m.leaf {
-		return &m.leaf, value, false
+// DeleteMin removes the entry with the smallest key from the map, returning its
+// key and value, and whether the map was not empty.
+//
+// Complexity: O(log n)
+func (m *Map[K, V]) DeleteMin() (key K, value V, deleted bool) {
+	if m.root != nil && m.root != m.leaf {
+		n := min(m.root, m.leaf)
+		key, value, deleted = n.key, n.value, true
+		m.setRoot(m.removeMin(m.root))
+		m.len--
+	}
+	return key, value, deleted
+}
+
+// DeleteMax removes the entry with the largest key from the map, returning its
+// key and value, and whether the map was not empty.
+//
+// Complexity: O(log n)
+func (m *Map[K, V]) DeleteMax() (key K, value V, deleted bool) {
+	if m.root != nil && m.root != m.leaf {
+		n := max(m.root, m.leaf)
+		key, value, deleted = n.key, n.value, true
+		m.setRoot(m.removeMax(m.root))
+		m.len--
+	}
+	return key, value, deleted
+}
+
+// setRoot installs n as the root of the tree after a deletion. When the last
+// node is removed, the double-black leaf bubbles up to the root and must be
+// replaced by the regular leaf.
+func (m *Map[K, V]) setRoot(n *node[K, V]) {
+	if n == m.bbleaf {
+		n = m.leaf
+	}
+	m.root = m.blacken(n)
+}
+
+// delete removes the node matching key from the subtree rooted at n. If cond is
+// not nil, the node is only removed if cond returns true for its value.
+func (m *Map[K, V]) delete(n *node[K, V], key K, cond func(V) bool) (node *node[K, V], value V, deleted bool) {
+	if n == m.leaf {
+		return m.leaf, value, false
 	}
+	n = m.own(n)
 	switch cmp := m.cmp(key, n.key); {
 	case cmp < 0:
-		n.a, value, deleted = m.delete(n.a, key)
+		n.a, value, deleted = m.delete(n.a, key, cond)
 		node = m.bubble(n)
 	case cmp > 0:
-		n.b, value, deleted = m.delete(n.b, key)
+		n.b, value, deleted = m.delete(n.b, key, cond)
 		node = m.bubble(n)
+	case cond != nil && !cond(n.value):
+		node = n
 	default:
 		value, deleted = n.value, true
 		node = m.remove(n)
@@ -270,65 +1805,94 @@ func (m *Map[K, V]) delete(n *node[K, V], key K) (node *node[K, V], value V, del
 }
 
 func (m *Map[K, V]) remove(n *node[K, V]) *node[K, V] {
-	if n == &m.leaf {
-		return &m.leaf
+	if n == m.leaf {
+		return m.leaf
 	}
-	if n.color == red && n.a == &m.leaf && n.b == &m.leaf {
-		return &m.leaf
+	if n.color == red && n.a == m.leaf && n.b == m.leaf {
+		return m.leaf
 	}
-	if n.color == black && n.a == &m.leaf && n.b == &m.leaf {
-		return &m.bbleaf
+	if n.color == black && n.a == m.leaf && n.b == m.leaf {
+		return m.bbleaf
 	}
-	if n.color == black && n.a == &m.leaf && n.b != &m.leaf && n.b.color == red {
-		n.b.color = black
-		return n.b
+	if n.color == black && n.a == m.leaf && n.b != m.leaf && n.b.color == red {
+		return m.blacken(n.b)
 	}
-	if n.color == black && n.b == &m.leaf && n.a != &m.leaf && n.a.color == red {
-		n.a.color = black
-		return n.a
+	if n.color == black && n.b == m.leaf && n.a != m.leaf && n.a.color == red {
+		return m.blacken(n.a)
 	}
 	// chasing same pointers twice. can optimize by
 	// making max return a *node and passing that in to removeMax.
-	max := max(n.a, &m.leaf)
-	n.key, n.value = max.key, max.value
+	n = m.own(n)
+	max := max(n.a, m.leaf)
+	n.key, n.value, n.version = max.key, max.value, max.version
 	n.a = m.removeMax(n.a)
 	n = m.bubble(n)
 	return n
 }
 
 func (m *Map[K, V]) removeMax(n *node[K, V]) *node[K, V] {
-	if n.b == &m.leaf {
+	if n.b == m.leaf {
 		return m.remove(n)
 	}
+	n = m.own(n)
 	n.b = m.removeMax(n.b)
 	return m.bubble(n)
 }
 
+func (m *Map[K, V]) removeMin(n *node[K, V]) *node[K, V] {
+	if n.a == m.leaf {
+		return m.remove(n)
+	}
+	n = m.own(n)
+	n.a = m.removeMin(n.a)
+	return m.bubble(n)
+}
+
 func (m *Map[K, V]) bubble(n *node[K, V]) *node[K, V] {
 	if n.a.color == bblack || n.b.color == bblack {
 		n.color = blacker(n.color)
 		n.a = m.redder(n.a)
 		n.b = m.redder(n.b)
-		return balance(n)
+		return m.balance(n)
 	}
-	return balance(n)
+	return m.balance(n)
 }
 
 func (m *Map[K, V]) redder(n *node[K, V]) *node[K, V] {
-	if n == &m.bbleaf {
-		return &m.leaf
+	if n == m.bbleaf {
+		return m.leaf
 	}
+	n = m.own(n)
 	n.color = redder(n.color)
 	return n
 }
 
-func blacken[K, V any](n *node[K, V]) *node[K, V] {
-	n.color = black
+// own returns n if it is owned by the map, or a copy of n owned by the map if
+// n is shared with a snapshot. The leaf sentinels are shared but never copied.
+func (m *Map[K, V]) own(n *node[K, V]) *node[K, V] {
+	if n.epoch == m.epoch || n == m.leaf || n == m.bbleaf {
+		return n
+	}
+	dup := *n
+	dup.epoch = m.epoch
+	return &dup
+}
+
+// blacken returns n colored black, copying it first if it is not owned by m.
+func (m *Map[K, V]) blacken(n *node[K, V]) *node[K, V] {
+	if n.color != black {
+		n = m.own(n)
+		n.color = black
+	}
 	return n
 }
 
+// redden does not write nodes which are already red, so it may be called on the
+// leaf sentinels shared with snapshots.
 func redden[K, V any](n *node[K, V]) *node[K, V] {
-	n.color = red
+	if n.color != red {
+		n.color = red
+	}
 	return n
 }
 
@@ -350,7 +1914,7 @@ func max[K, V any](node, leaf *node[K, V]) *node[K, V] {
 	return node
 }
 
-func balance[K, V any](n *node[K, V]) *node[K, V] {
+func (m *Map[K, V]) balance(n *node[K, V]) *node[K, V] {
 	var x, y, z *node[K, V]
 	var a, b, c, d *node[K, V]
 	okasakiCase := false
@@ -373,10 +1937,11 @@ func balance[K, V any](n *node[K, V]) *node[K, V] {
 		okasakiCase = true
 	}
 	if okasakiCase {
+		x, y, z = m.own(x), m.own(y), m.own(z)
 		x.a, x.b, z.a, z.b = a, b, c, d
-		y.a, y.b = x, z
+		y.a, y.b = update(x), update(z)
 		x.color, y.color, z.color = black, red, black
-		return y
+		return update(y)
 	}
 	mightCase := false
 	switch {
@@ -397,52 +1962,67 @@ func balance[K, V any](n *node[K, V]) *node[K, V] {
 		a, b, c, d = x.a, y.a, z.a, z.b
 		mightCase = true
 	default:
-		c1, ok := deleteCase1(n)
+		c1, ok := m.deleteCase1(n)
 		if ok {
 			return c1
 		}
-		c2, ok := deleteCase2(n)
+		c2, ok := m.deleteCase2(n)
 		if ok {
 			return c2
 		}
 	}
 	if mightCase {
+		x, y, z = m.own(x), m.own(y), m.own(z)
 		x.a, x.b, z.a, z.b = a, b, c, d
-		y.a, y.b = x, z
+		y.a, y.b = update(x), update(z)
 		x.color, y.color, z.color = black, black, black
-		return y
+		return update(y)
+	}
+	return update(n)
+}
+
+// update recomputes the augmented data of n from its children, it must be
+// called on each node whose children were modified, from the bottom up.
+func update[K, V any](n *node[K, V]) *node[K, V] {
+	n.maxVersion = n.version
+	if v := n.a.maxVersion; v > n.maxVersion {
+		n.maxVersion = v
+	}
+	if v := n.b.maxVersion; v > n.maxVersion {
+		n.maxVersion = v
 	}
+	n.size = 1 + n.a.size + n.b.size
 	return n
 }
 
-func deleteCase1[K, V any](n *node[K, V]) (*node[K, V], bool) {
+func (m *Map[K, V]) deleteCase1(n *node[K, V]) (*node[K, V], bool) {
 	cond := n.color == bblack && n.b.color == nblack && n.b.a.color == black && n.b.b.color == black
 	if !cond {
 		return n, false
 	}
-	x, y, z := n, n.b.a, n.b
+	x, y, z := m.own(n), m.own(n.b.a), m.own(n.b)
 	a, b, c, d := x.a, y.a, y.b, z.b
 	x.a, x.b = a, b
-	z.a, z.b = c, redden(d)
+	z.a, z.b = c, redden(m.own(d))
 	z.color = black
-	y.a, y.b = x, balance(z)
+	y.a, y.b = update(x), m.balance(z)
 	x.color, y.color, z.color = black, black, black
-	return y, true
+	return update(y), true
 }
 
-func deleteCase2[K, V any](n *node[K, V]) (*node[K, V], bool) {
+func (m *Map[K, V]) deleteCase2(n *node[K, V]) (*node[K, V], bool) {
 	cond := n.color == bblack && n.a.color == nblack && n.a.a.color == black && n.a.b.color == black
 	if !cond {
 		return n, false
 	}
-	x, y, z := n.a, n.a.b, n
+	x, y, z := m.own(n.a), m.own(n.a.b), m.own(n)
 	a, b, c, d := x.a, y.a, y.b, z.b
-	x.a, x.b = redden(a), b
+	x.a, x.b = redden(m.own(a)), b
 	z.a, z.b = c, d
 	x.color = black
-	y.a, y.b = balance(x), z
+	y.a, y.b = m.balance(x), update(z)
 	x.color, y.color, z.color = black, black, black
-	return y, true
+	return update(y), true
 }
 
 func redder(c color) color {
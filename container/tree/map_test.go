@@ -1,9 +1,12 @@
 package tree
 
 import (
+	"errors"
 	"fmt"
 	"math"
+	"math/rand"
 	"sort"
+	"strings"
 	"testing"
 	"testing/quick"
 
@@ -59,6 +62,16 @@ func TestMap(t *testing.T) {
 			scenario: "searching for a range of entries greater or equal to a given key",
 			function: testMapSearchAndRange,
 		},
+
+		{
+			scenario: "the floor of a key is the entry with the highest key that is lower or equal",
+			function: testMapFloor,
+		},
+
+		{
+			scenario: "the ceiling of a key is the entry with the lowest key that is greater or equal",
+			function: testMapCeiling,
+		},
 	}
 
 	for _, test := range tests {
@@ -74,6 +87,12 @@ func testMapEmpty(t *testing.T, m *Map[int32, int64]) {
 	if n := m.Len(); n != 0 {
 		t.Errorf("wrong number of map entries: got=%d want=0", n)
 	}
+	if k, v, found := m.Min(); found {
+		t.Errorf("found min entry in empty map: key=%d value=%d", k, v)
+	}
+	if k, v, found := m.Max(); found {
+		t.Errorf("found max entry in empty map: key=%d value=%d", k, v)
+	}
 }
 
 func testMapInsertAndLookup(t *testing.T, m *Map[int32, int64]) {
@@ -439,6 +458,58 @@ func testMapSearchNotExist(t *testing.T, m *Map[int32, int64]) {
 	}
 }
 
+func testMapFloor(t *testing.T, m *Map[int32, int64]) {
+	testMapBound(t, m, m.Floor, func(k, key int32) bool { return k <= key }, func(a, b int32) bool { return a > b })
+}
+
+func testMapCeiling(t *testing.T, m *Map[int32, int64]) {
+	testMapBound(t, m, m.Ceiling, func(k, key int32) bool { return k >= key }, func(a, b int32) bool { return a < b })
+}
+
+// testMapBound verifies a bound search function of the map against a brute
+// force scan of the keys. match reports whether a key of the map is a candidate
+// for the searched key, and better whether a candidate is closer to the
+// searched key than another.
+func testMapBound(t *testing.T, m *Map[int32, int64], bound func(int32) (int32, int64, bool), match func(k, key int32) bool, better func(a, b int32) bool) {
+	f := func(keys map[int32]int64, searches []int32) bool {
+		m.Init(compare.Function[int32])
+
+		for k, v := range keys {
+			m.Insert(k, v)
+		}
+		for k := range keys {
+			searches = append(searches, k, k-1, k+1)
+		}
+
+		for _, k := range searches {
+			expectKey, expectValue, expectFound := int32(0), int64(0), false
+			for existKey, existValue := range keys {
+				if match(existKey, k) && (!expectFound || better(existKey, expectKey)) {
+					expectKey, expectValue, expectFound = existKey, existValue, true
+				}
+			}
+
+			key, value, found := bound(k)
+			if found != expectFound {
+				t.Errorf("key search mismatch: key=%d got=%t want=%t", k, found, expectFound)
+				return false
+			} else if key != expectKey {
+				t.Errorf("wrong key returned: got=%d want=%d", key, expectKey)
+				return false
+			} else if value != expectValue {
+				t.Errorf("wrong value returned for key=%d: got=%d want=%d", k, value, expectValue)
+				return false
+			}
+		}
+
+		return true
+	}
+
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
 func testMapSearchAndRange(t *testing.T, m *Map[int32, int64]) {
 	f := func(keys map[int32]int64) bool {
 		m.Init(compare.Function[int32])
@@ -510,25 +581,2096 @@ func testMapSearchAndRange(t *testing.T, m *Map[int32, int64]) {
 	}
 }
 
-func (m *Map[K, V]) checkInvariants() {
-	if m.root.color != black {
-		panic("root must be black")
+func TestMapDeleteAll(t *testing.T) {
+	m := NewMap[int, int](compare.Function[int])
+
+	for round := 0; round < 3; round++ {
+		for i := 0; i < 100; i++ {
+			m.Insert(i, i)
+		}
+		m.checkInvariants()
+
+		for i := 0; i < 100; i++ {
+			if _, deleted := m.Delete(i); !deleted {
+				t.Fatalf("key=%d not deleted", i)
+			}
+			m.checkInvariants()
+		}
+
+		if n := m.Len(); n != 0 {
+			t.Fatalf("wrong number of entries in map: got=%d want=0", n)
+		}
+		if k, _, found := m.Min(); found {
+			t.Fatalf("found min entry in empty map: key=%d", k)
+		}
 	}
-	ys := make([]int, 0)
-	xs := &ys
-	m.check(m.root, 0, xs)
-	i := 1
-	for i < len(*xs) {
-		if (*xs)[i-1] != (*xs)[i] {
-			fmt.Println(xs)
-			panic("black height not same for all the leaves")
+}
+
+func TestMapSuccessorPredecessor(t *testing.T) {
+	f := func(keys map[int32]int64, searches []int32) bool {
+		m := NewMap[int32, int64](compare.Function[int32])
+		for k, v := range keys {
+			m.Insert(k, v)
 		}
-		i++
+		for k := range keys {
+			searches = append(searches, k, k-1, k+1)
+		}
+
+		for _, k := range searches {
+			succ, pred := int32(0), int32(0)
+			hasSucc, hasPred := false, false
+			for existKey := range keys {
+				if existKey > k && (!hasSucc || existKey < succ) {
+					succ, hasSucc = existKey, true
+				}
+				if existKey < k && (!hasPred || existKey > pred) {
+					pred, hasPred = existKey, true
+				}
+			}
+
+			if key, value, found := m.Successor(k); found != hasSucc || key != succ || (found && value != keys[succ]) {
+				t.Errorf("wrong successor of key=%d: got=%d,%d,%t want=%d,%t", k, key, value, found, succ, hasSucc)
+				return false
+			}
+			if key, value, found := m.Predecessor(k); found != hasPred || key != pred || (found && value != keys[pred]) {
+				t.Errorf("wrong predecessor of key=%d: got=%d,%d,%t want=%d,%t", k, key, value, found, pred, hasPred)
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+
+	// Stepping through the map with Successor visits every key in order.
+	m := NewMap[int, int](compare.Function[int])
+	for i := 0; i < 100; i += 2 {
+		m.Insert(i, -i)
+	}
+	n := 0
+	for k, v, ok := m.Successor(-1); ok; k, v, ok = m.Successor(k) {
+		if k != 2*n || v != -k {
+			t.Fatalf("wrong entry at step %d: got=%d,%d want=%d,%d", n, k, v, 2*n, -2*n)
+		}
+		n++
+	}
+	if n != 50 {
+		t.Errorf("wrong number of steps: got=%d want=50", n)
+	}
+}
+
+func TestMapStrict(t *testing.T) {
+	type key struct {
+		a int
+		b int
+	}
+
+	// This comparison function has a bug, it forgets to compare the b field.
+	cmp := func(k1, k2 key) int { return compare.Function(k1.a, k2.a) }
+	equal := func(k1, k2 key) bool { return k1 == k2 }
+
+	m := NewMapStrict[key, int](cmp, equal)
+	m.Insert(key{a: 1, b: 1}, 1)
+
+	if previous, replaced := m.Insert(key{a: 1, b: 1}, 2); !replaced {
+		t.Error("inserting an identical key did not replace the previous entry")
+	} else if previous != 1 {
+		t.Errorf("wrong previous value returned: got=%d want=1", previous)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("inserting distinct keys comparing equal did not panic")
+		}
+		if v, _ := m.Lookup(key{a: 1, b: 1}); v != 2 {
+			t.Errorf("the map was modified by the conflicting insert: got=%d want=2", v)
+		}
+		m.checkInvariants()
+	}()
+
+	m.Insert(key{a: 1, b: 2}, 3)
+}
+
+func TestMapNotInitialized(t *testing.T) {
+	tests := []struct {
+		method string
+		insert func(*Map[int, int])
+	}{
+		{"Insert", func(m *Map[int, int]) { m.Insert(1, 1) }},
+		{"InsertStrict", func(m *Map[int, int]) { m.InsertStrict(1, 1) }},
+		{"LookupOrInsert", func(m *Map[int, int]) { m.LookupOrInsert(1, 1) }},
+		{"InsertWith", func(m *Map[int, int]) { m.InsertWith(1, func(int, bool) int { return 1 }) }},
+		{"Merge", func(m *Map[int, int]) { m.Merge(NewMap[int, int](compare.Function[int]), nil) }},
+		{"Increment", func(m *Map[int, int]) { Increment(m, 1, 1) }},
+	}
+
+	for _, test := range tests {
+		t.Run(test.method, func(t *testing.T) {
+			m := new(Map[int, int])
+			defer func() {
+				want := "tree: " + test.method + " called on a map which was not initialized"
+				if r := recover(); r != want {
+					t.Errorf("wrong panic: got=%v want=%q", r, want)
+				}
+				if n := m.Len(); n != 0 {
+					t.Errorf("wrong map length: got=%d want=0", n)
+				}
+				if _, ok := m.Lookup(1); ok {
+					t.Error("key found in a map which was not initialized")
+				}
+			}()
+			test.insert(m)
+		})
+	}
+
+	m := new(Map[int, int])
+	if _, ok := m.Delete(1); ok {
+		t.Error("key deleted from a map which was not initialized")
+	}
+	if _, _, ok := m.DeleteMin(); ok {
+		t.Error("minimum deleted from a map which was not initialized")
+	}
+}
+
+func TestMapInsertStrict(t *testing.T) {
+	type key struct {
+		a int
+		b int
+	}
+
+	cmp := func(k1, k2 key) int { return compare.Function(k1.a, k2.a) }
+	equal := func(k1, k2 key) bool { return k1 == k2 }
+
+	m := NewMapStrict[key, int](cmp, equal)
+	if _, replaced, err := m.InsertStrict(key{a: 1, b: 1}, 1); err != nil || replaced {
+		t.Fatalf("wrong result of inserting a new key: got=%t,%v want=false,<nil>", replaced, err)
+	}
+	if previous, replaced, err := m.InsertStrict(key{a: 1, b: 1}, 2); err != nil || !replaced || previous != 1 {
+		t.Errorf("wrong result of inserting an identical key: got=%d,%t,%v want=1,true,<nil>", previous, replaced, err)
+	}
+	if _, replaced, err := m.InsertStrict(key{a: 1, b: 2}, 3); !errors.Is(err, ErrKeyMismatch) || replaced {
+		t.Errorf("wrong result of inserting distinct keys comparing equal: got=%t,%v want=false,%v", replaced, err, ErrKeyMismatch)
+	}
+	if v, _ := m.Lookup(key{a: 1, b: 1}); v != 2 {
+		t.Errorf("the map was modified by the conflicting insert: got=%d want=2", v)
+	}
+	if n := m.Len(); n != 1 {
+		t.Errorf("wrong map length: got=%d want=1", n)
+	}
+	m.checkInvariants()
+
+	// Without an equal function, the map is not strict and keys comparing
+	// equal are replaced.
+	m = NewMap[key, int](cmp)
+	m.InsertStrict(key{a: 1, b: 1}, 1)
+	if previous, replaced, err := m.InsertStrict(key{a: 1, b: 2}, 2); err != nil || !replaced || previous != 1 {
+		t.Errorf("wrong result of replacing a key in a map which is not strict: got=%d,%t,%v want=1,true,<nil>", previous, replaced, err)
+	}
+}
+
+func TestReduce(t *testing.T) {
+	m := NewMap[int, string](compare.Function[int])
+
+	if s := Reduce(m, "", func(acc string, k int, v string) string { return acc + v }); s != "" {
+		t.Errorf("wrong result of reducing an empty map: %q", s)
+	}
+
+	m.Insert(3, "c")
+	m.Insert(1, "a")
+	m.Insert(2, "b")
+
+	if s := Reduce(m, ">", func(acc string, k int, v string) string { return acc + v }); s != ">abc" {
+		t.Errorf("wrong result of reducing map entries: got=%q want=%q", s, ">abc")
+	}
+
+	if sum := Reduce(m, 0, func(acc, k int, v string) int { return acc + k }); sum != 6 {
+		t.Errorf("wrong sum of map keys: got=%d want=6", sum)
+	}
+}
+
+func TestMapSelectInRange(t *testing.T) {
+	m := NewMap[int, int](compare.Function[int])
+	for i := 0; i < 100; i += 10 {
+		m.Insert(i, -i)
+	}
+
+	tests := []struct {
+		lo, hi, i int
+		key       int
+		found     bool
+	}{
+		{lo: 0, hi: 90, i: 0, key: 0, found: true},
+		{lo: 15, hi: 55, i: 0, key: 20, found: true},
+		{lo: 15, hi: 55, i: 3, key: 50, found: true},
+		{lo: 15, hi: 55, i: 4, found: false},
+		{lo: 15, hi: 55, i: -1, found: false},
+		{lo: 91, hi: 100, i: 0, found: false},
+		{lo: 50, hi: 50, i: 0, key: 50, found: true},
+	}
+
+	for _, test := range tests {
+		k, v, found := m.SelectInRange(test.lo, test.hi, test.i)
+		if found != test.found {
+			t.Errorf("SelectInRange(%d, %d, %d): wrong result: got=%t want=%t", test.lo, test.hi, test.i, found, test.found)
+		} else if found && (k != test.key || v != -test.key) {
+			t.Errorf("SelectInRange(%d, %d, %d): wrong entry: got=%d:%d want=%d:%d", test.lo, test.hi, test.i, k, v, test.key, -test.key)
+		}
+	}
+}
+
+func TestMapKeysValues(t *testing.T) {
+	f := func(entries map[int32]int32) bool {
+		m := NewMap[int32, int32](compare.Function[int32])
+		for k, v := range entries {
+			m.Insert(k, v)
+		}
+
+		wantKeys := make([]int32, 0, len(entries))
+		for k := range entries {
+			wantKeys = append(wantKeys, k)
+		}
+		sort.Slice(wantKeys, func(i, j int) bool { return wantKeys[i] < wantKeys[j] })
+		wantValues := make([]int32, len(wantKeys))
+		for i, k := range wantKeys {
+			wantValues[i] = entries[k]
+		}
+
+		keys, values := m.Keys(), m.Values()
+		if fmt.Sprint(keys) != fmt.Sprint(wantKeys) {
+			t.Errorf("wrong keys: got=%v want=%v", keys, wantKeys)
+			return false
+		}
+		if fmt.Sprint(values) != fmt.Sprint(wantValues) {
+			t.Errorf("wrong values: got=%v want=%v", values, wantValues)
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+
+	m := NewMap[int, int](compare.Function[int])
+	m.Insert(1, 10)
+	m.Insert(2, 20)
+
+	keys, values := m.Keys(), m.Values()
+	keys[0], values[0] = 100, 1000
+	if v, ok := m.Lookup(1); !ok || v != 10 {
+		t.Errorf("modifying the returned slices altered the map: got=%d,%t want=10,true", v, ok)
+	}
+	if k := m.Keys(); k[0] != 1 {
+		t.Errorf("modifying the returned slices altered the map keys: got=%d want=1", k[0])
+	}
+
+	var empty Map[int, int]
+	if n := len(empty.Keys()) + len(empty.Values()); n != 0 {
+		t.Errorf("wrong number of keys and values of empty map: got=%d want=0", n)
+	}
+}
+
+func TestMapValuesInRange(t *testing.T) {
+	m := NewMap[int, int](compare.Function[int])
+	for i := 0; i < 100; i += 10 {
+		m.Insert(i, -i)
+	}
+
+	tests := []struct {
+		lo, hi int
+		want   string
+	}{
+		{lo: 15, hi: 55, want: "[-20 -30 -40 -50]"},
+		{lo: 50, hi: 50, want: "[-50]"},
+		{lo: -10, hi: 5, want: "[0]"},
+		{lo: 51, hi: 59, want: "[]"},
+		{lo: 60, hi: 50, want: "[]"},
+	}
+
+	for _, test := range tests {
+		values := m.ValuesInRange(test.lo, test.hi)
+		if got := fmt.Sprint(values); got != test.want {
+			t.Errorf("ValuesInRange(%d, %d): got=%s want=%s", test.lo, test.hi, got, test.want)
+		}
+		if len(values) != cap(values) {
+			t.Errorf("ValuesInRange(%d, %d): wrong capacity: got=%d want=%d", test.lo, test.hi, cap(values), len(values))
+		}
+	}
+}
+
+func TestMapPage(t *testing.T) {
+	m := NewMap[int, int](compare.Function[int])
+	for i := 0; i < 10; i++ {
+		m.Insert(2*i, -2*i)
+	}
+
+	pages := [][]int{}
+	entries, next, more := m.FirstPage(4)
+	for {
+		keys := []int{}
+		for _, e := range entries {
+			if e.Value != -e.Key {
+				t.Errorf("wrong value for key=%d: got=%d want=%d", e.Key, e.Value, -e.Key)
+			}
+			keys = append(keys, e.Key)
+		}
+		pages = append(pages, keys)
+		if !more {
+			break
+		}
+		entries, next, more = m.Page(next, 4)
+	}
+	if fmt.Sprint(pages) != "[[0 2 4 6] [8 10 12 14] [16 18]]" {
+		t.Errorf("wrong pages: got=%v want=[[0 2 4 6] [8 10 12 14] [16 18]]", pages)
+	}
+
+	tests := []struct {
+		after int
+		limit int
+		keys  string
+		next  int
+		more  bool
+	}{
+		{after: 3, limit: 2, keys: "[4 6]", next: 6, more: true},
+		{after: 4, limit: 2, keys: "[6 8]", next: 8, more: true},
+		{after: 14, limit: 2, keys: "[16 18]", next: 18, more: false},
+		{after: 15, limit: 10, keys: "[16 18]", next: 18, more: false},
+		{after: 18, limit: 2, keys: "[]", next: 18, more: false},
+		{after: -5, limit: 1, keys: "[0]", next: 0, more: true},
+		{after: 2, limit: 0, keys: "[]", next: 2, more: false},
+	}
+
+	for _, test := range tests {
+		entries, next, more := m.Page(test.after, test.limit)
+		keys := []int{}
+		for _, e := range entries {
+			keys = append(keys, e.Key)
+		}
+		if fmt.Sprint(keys) != test.keys || next != test.next || more != test.more {
+			t.Errorf("Page(%d, %d): got=%v,%d,%t want=%s,%d,%t", test.after, test.limit, keys, next, more, test.keys, test.next, test.more)
+		}
+	}
+
+	if entries, _, more := new(Map[int, int]).FirstPage(10); len(entries) != 0 || more {
+		t.Errorf("wrong first page of empty map: got=%v,%t", entries, more)
+	}
+}
+
+func TestMapRangeSummary(t *testing.T) {
+	m := NewMap[int, int](compare.Function[int])
+	for i := 0; i < 100; i += 10 {
+		m.Insert(i, -i)
+	}
+
+	tests := []struct {
+		lo, hi int
+		count  int
+		minKey int
+		maxKey int
+		found  bool
+	}{
+		{lo: 0, hi: 90, count: 10, minKey: 0, maxKey: 90, found: true},
+		{lo: 15, hi: 55, count: 4, minKey: 20, maxKey: 50, found: true},
+		{lo: 50, hi: 50, count: 1, minKey: 50, maxKey: 50, found: true},
+		{lo: 51, hi: 59, found: false},
+		{lo: 60, hi: 50, found: false},
+		{lo: -100, hi: 1000, count: 10, minKey: 0, maxKey: 90, found: true},
+	}
+
+	for _, test := range tests {
+		count, minKey, maxKey, found := m.RangeSummary(test.lo, test.hi)
+		if found != test.found {
+			t.Errorf("RangeSummary(%d, %d): wrong result: got=%t want=%t", test.lo, test.hi, found, test.found)
+		}
+		if count != test.count || minKey != test.minKey || maxKey != test.maxKey {
+			t.Errorf("RangeSummary(%d, %d): wrong summary: got=%d,[%d,%d] want=%d,[%d,%d]", test.lo, test.hi, count, minKey, maxKey, test.count, test.minKey, test.maxKey)
+		}
+	}
+}
+
+func TestMapCountGroups(t *testing.T) {
+	m := NewMap[int, int](compare.Function[int])
+	tens := func(a, b int) int { return compare.Function(a/10, b/10) }
+
+	if n := m.CountGroups(tens); n != 0 {
+		t.Errorf("wrong number of groups in empty map: got=%d want=0", n)
+	}
+
+	for _, k := range []int{1, 5, 9, 10, 35, 36, 70, 99} {
+		m.Insert(k, k)
+	}
+
+	if n := m.CountGroups(tens); n != 5 {
+		t.Errorf("wrong number of groups: got=%d want=5", n)
+	}
+	if n := m.CountGroups(compare.Function[int]); n != 8 {
+		t.Errorf("wrong number of groups with identity grouping: got=%d want=8", n)
+	}
+}
+
+func TestMapIntersectSorted(t *testing.T) {
+	m := NewMap[int, int](compare.Function[int])
+	for i := 0; i < 100; i += 3 {
+		m.Insert(i, -i)
+	}
+
+	keys := []int{-1, 0, 1, 2, 3, 3, 4, 9, 50, 51, 99, 100, 200}
+	want := []int{0, 3, 9, 51, 99}
+	got := []int{}
+
+	m.IntersectSorted(keys, func(k, v int) bool {
+		if v != -k {
+			t.Errorf("wrong value for key=%d: got=%d want=%d", k, v, -k)
+		}
+		got = append(got, k)
+		return true
+	})
+
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("wrong intersection: got=%v want=%v", got, want)
+	}
+
+	got = got[:0]
+	m.IntersectSorted(keys, func(k, v int) bool {
+		got = append(got, k)
+		return len(got) < 2
+	})
+
+	if fmt.Sprint(got) != fmt.Sprint(want[:2]) {
+		t.Errorf("wrong intersection after stopping: got=%v want=%v", got, want[:2])
+	}
+}
+
+func TestMapRangeFromMin(t *testing.T) {
+	m := NewMap[int, int](compare.Function[int])
+	for i := 1; i <= 1000; i++ {
+		m.Insert(i, -i)
+	}
+
+	keys := []int{}
+	m.Range(500, func(k, v int) bool {
+		if v != -k {
+			t.Errorf("wrong value for key=%d: got=%d want=%d", k, v, -k)
+		}
+		keys = append(keys, k)
+		return true
+	})
+	if len(keys) != 501 {
+		t.Fatalf("wrong number of keys: got=%d want=501", len(keys))
+	}
+	for i, k := range keys {
+		if k != 500+i {
+			t.Fatalf("wrong key at index %d: got=%d want=%d", i, k, 500+i)
+		}
+	}
+
+	// Seeking to the lower bound must not visit the entries below it, so the
+	// number of comparisons is logarithmic in the size of the map.
+	c := compare.Counting(compare.Function[int])
+	m.Init(c.Compare)
+	for i := 1; i <= 1000; i++ {
+		m.Insert(i, -i)
+	}
+	c.Reset()
+	m.Range(990, func(k, v int) bool { return true })
+	if n := c.Count(); n > 2*20 {
+		t.Errorf("too many comparisons to seek to the lower bound: got=%d want<=%d", n, 2*20)
+	}
+}
+
+func TestMapRangeReverse(t *testing.T) {
+	f := func(keys []int32) bool {
+		m := NewMap[int32, int32](compare.Function[int32])
+		for _, k := range keys {
+			m.Insert(k, -k)
+		}
+
+		ascending := []int32{}
+		m.Range(math.MinInt32, func(k, _ int32) bool {
+			ascending = append(ascending, k)
+			return true
+		})
+
+		descending := []int32{}
+		m.RangeReverse(func(k, v int32) bool {
+			if v != -k {
+				t.Errorf("wrong value for key=%d: got=%d want=%d", k, v, -k)
+			}
+			descending = append(descending, k)
+			return true
+		})
+
+		if len(descending) != len(ascending) {
+			t.Errorf("wrong number of keys: got=%d want=%d", len(descending), len(ascending))
+			return false
+		}
+		for i, k := range descending {
+			if want := ascending[len(ascending)-1-i]; k != want {
+				t.Errorf("wrong key at index %d: got=%d want=%d", i, k, want)
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+
+	m := NewMap[int, int](compare.Function[int])
+	for i := 0; i < 10; i++ {
+		m.Insert(i, i)
+	}
+	got := []int{}
+	m.RangeReverse(func(k, _ int) bool {
+		got = append(got, k)
+		return len(got) < 3
+	})
+	if fmt.Sprint(got) != "[9 8 7]" {
+		t.Errorf("wrong keys after stopping the iteration: got=%v want=[9 8 7]", got)
+	}
+
+	new(Map[int, int]).RangeReverse(func(int, int) bool {
+		t.Error("RangeReverse called f on an empty map")
+		return true
+	})
+}
+
+func TestMapRangeBetween(t *testing.T) {
+	f := func(keys []int16, lo, hi int16) bool {
+		m := NewMap[int16, int16](compare.Function[int16])
+		for _, k := range keys {
+			m.Insert(k, -k)
+		}
+
+		want := []int16{}
+		m.Range(math.MinInt16, func(k, _ int16) bool {
+			if k >= lo && k <= hi {
+				want = append(want, k)
+			}
+			return true
+		})
+
+		got := []int16{}
+		m.RangeBetween(lo, hi, func(k, v int16) bool {
+			if v != -k {
+				t.Errorf("wrong value for key=%d: got=%d want=%d", k, v, -k)
+			}
+			got = append(got, k)
+			return true
+		})
+
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("RangeBetween(%d, %d): wrong keys: got=%v want=%v", lo, hi, got, want)
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+
+	m := NewMap[int, int](compare.Function[int])
+	for i := 0; i < 100; i++ {
+		m.Insert(i, i)
+	}
+	got := []int{}
+	m.RangeBetween(10, 20, func(k, _ int) bool {
+		got = append(got, k)
+		return len(got) < 3
+	})
+	if fmt.Sprint(got) != "[10 11 12]" {
+		t.Errorf("wrong keys after stopping the iteration: got=%v want=[10 11 12]", got)
+	}
+	m.RangeBetween(20, 10, func(k, _ int) bool {
+		t.Errorf("RangeBetween called f with key=%d on an empty interval", k)
+		return true
+	})
+}
+
+func TestMapCountBetween(t *testing.T) {
+	f := func(keys []int16, lo, hi int16) bool {
+		m := NewMap[int16, int16](compare.Function[int16])
+		for _, k := range keys {
+			m.Insert(k, k)
+		}
+
+		want := 0
+		m.RangeBetween(lo, hi, func(int16, int16) bool {
+			want++
+			return true
+		})
+
+		if n := m.CountBetween(lo, hi); n != want {
+			t.Errorf("CountBetween(%d, %d): got=%d want=%d", lo, hi, n, want)
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+
+	m := NewMap[int, int](compare.Function[int])
+	for i := 0; i < 100; i += 10 {
+		m.Insert(i, i)
+	}
+
+	tests := []struct {
+		lo, hi int
+		count  int
+	}{
+		{lo: 0, hi: 90, count: 10},
+		{lo: 10, hi: 30, count: 3},
+		{lo: 11, hi: 29, count: 1},
+		{lo: 11, hi: 19, count: 0},
+		{lo: -100, hi: 1000, count: 10},
+		{lo: 50, hi: 50, count: 1},
+		{lo: 60, hi: 40, count: 0},
+	}
+
+	for _, test := range tests {
+		if n := m.CountBetween(test.lo, test.hi); n != test.count {
+			t.Errorf("CountBetween(%d, %d): got=%d want=%d", test.lo, test.hi, n, test.count)
+		}
+	}
+
+	if n := new(Map[int, int]).CountBetween(0, 10); n != 0 {
+		t.Errorf("CountBetween on empty map: got=%d want=0", n)
+	}
+}
+
+func TestMapRangeReverseFrom(t *testing.T) {
+	m := NewMap[int, int](compare.Function[int])
+	for i := 0; i < 100; i += 3 {
+		m.Insert(i, -i)
+	}
+
+	tests := []struct {
+		lo, hi int
+		want   []int
+	}{
+		{lo: 10, hi: 20, want: []int{18, 15, 12}},
+		{lo: 12, hi: 18, want: []int{18, 15, 12}},
+		{lo: 90, hi: 200, want: []int{99, 96, 93, 90}},
+		{lo: -10, hi: 5, want: []int{3, 0}},
+		{lo: 13, hi: 14, want: []int{}},
+		{lo: 20, hi: 10, want: []int{}},
+		{lo: 100, hi: 200, want: []int{}},
+	}
+
+	for _, test := range tests {
+		got := []int{}
+		m.RangeReverseFrom(test.lo, test.hi, func(k, v int) bool {
+			if v != -k {
+				t.Errorf("wrong value for key=%d: got=%d want=%d", k, v, -k)
+			}
+			got = append(got, k)
+			return true
+		})
+		if fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("RangeReverseFrom(%d, %d): wrong keys: got=%v want=%v", test.lo, test.hi, got, test.want)
+		}
+	}
+
+	got := []int{}
+	m.RangeReverseFrom(0, 50, func(k, v int) bool {
+		got = append(got, k)
+		return len(got) < 3
+	})
+	if fmt.Sprint(got) != "[48 45 42]" {
+		t.Errorf("wrong keys after stopping the iteration: got=%v want=[48 45 42]", got)
+	}
+}
+
+func TestMapEqualRange(t *testing.T) {
+	m := NewMap[int, int](compare.Function[int])
+	for i := 0; i < 100; i += 3 {
+		m.Insert(i, -i)
+	}
+
+	// Groups keys by tens, e.g. 30 to 39.
+	tens := func(a, b int) int { return compare.Function(a/10, b/10) }
+
+	tests := []struct {
+		key  int
+		want []int
+	}{
+		{key: 0, want: []int{0, 3, 6, 9}},
+		{key: 35, want: []int{30, 33, 36, 39}},
+		{key: 47, want: []int{42, 45, 48}},
+		{key: 99, want: []int{90, 93, 96, 99}},
+		{key: 100, want: []int{}},
+		{key: -10, want: []int{}},
+	}
+
+	for _, test := range tests {
+		got := []int{}
+		m.EqualRange(test.key, tens, func(k, v int) bool {
+			if v != -k {
+				t.Errorf("wrong value for key=%d: got=%d want=%d", k, v, -k)
+			}
+			got = append(got, k)
+			return true
+		})
+		if fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("EqualRange(%d): wrong keys: got=%v want=%v", test.key, got, test.want)
+		}
+	}
+
+	got := []int{}
+	m.EqualRange(35, tens, func(k, v int) bool {
+		got = append(got, k)
+		return len(got) < 2
+	})
+	if fmt.Sprint(got) != "[30 33]" {
+		t.Errorf("wrong keys after stopping: got=%v want=[30 33]", got)
+	}
+}
+
+func TestIncrement(t *testing.T) {
+	m := NewMap[string, int](compare.Function[string])
+	model := make(map[string]int)
+	prng := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 1000; i++ {
+		k := fmt.Sprint(prng.Intn(50))
+		d := prng.Intn(10) - 5
+		model[k] += d
+
+		if v := Increment(m, k, d); v != model[k] {
+			t.Fatalf("wrong value returned for key=%s: got=%d want=%d", k, v, model[k])
+		}
+	}
+	m.checkInvariants()
+
+	if n := m.Len(); n != len(model) {
+		t.Errorf("wrong number of entries: got=%d want=%d", n, len(model))
+	}
+	for k, want := range model {
+		if v, _ := m.Lookup(k); v != want {
+			t.Errorf("wrong value for key=%s: got=%d want=%d", k, v, want)
+		}
+	}
+
+	version := m.Version()
+	Increment(m, "0", 1)
+	changed := []string{}
+	m.RangeChangedSince(version, func(k string, _ int) bool {
+		changed = append(changed, k)
+		return true
+	})
+	if fmt.Sprint(changed) != "[0]" {
+		t.Errorf("wrong changed entries after increment: got=%v want=[0]", changed)
+	}
+}
+
+func TestMapLookupOr(t *testing.T) {
+	m := NewMap[string, int](compare.Function[string])
+	m.Insert("a", 1)
+	m.Insert("zero", 0)
+
+	tests := []struct {
+		key  string
+		want int
+	}{
+		{key: "a", want: 1},
+		{key: "zero", want: 0},
+		{key: "b", want: -1},
+	}
+
+	for _, test := range tests {
+		if got := m.LookupOr(test.key, -1); got != test.want {
+			t.Errorf("LookupOr(%q): got=%d want=%d", test.key, got, test.want)
+		}
+	}
+
+	if got := new(Map[string, int]).LookupOr("a", 42); got != 42 {
+		t.Errorf("LookupOr on empty map: got=%d want=42", got)
+	}
+}
+
+func TestMapMustLookup(t *testing.T) {
+	m := NewMap[string, int](compare.Function[string])
+	m.Insert("a", 1)
+
+	if got := m.MustLookup("a"); got != 1 {
+		t.Errorf("MustLookup: got=%d want=1", got)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("MustLookup of a missing key did not panic")
+		}
+	}()
+	m.MustLookup("b")
+}
+
+func TestMapRankSelect(t *testing.T) {
+	f := func(inserts, deletes []int16) bool {
+		m := NewMap[int16, int16](compare.Function[int16])
+		for _, k := range inserts {
+			m.Insert(k, -k)
+		}
+		for _, k := range deletes {
+			m.Delete(k)
+		}
+		m.checkInvariants()
+
+		keys := []int16{}
+		m.Range(math.MinInt16, func(k, _ int16) bool {
+			keys = append(keys, k)
+			return true
+		})
+
+		for i, k := range keys {
+			if rank, found := m.Rank(k); rank != i || !found {
+				t.Errorf("Rank(%d): got=%d,%t want=%d,true", k, rank, found, i)
+				return false
+			}
+			if key, value, found := m.Select(i); key != k || value != -k || !found {
+				t.Errorf("Select(%d): got=%d,%d,%t want=%d,%d,true", i, key, value, found, k, -k)
+				return false
+			}
+		}
+		for _, k := range deletes {
+			if _, exists := m.Lookup(k); exists {
+				continue
+			}
+			want := 0
+			for want < len(keys) && keys[want] < k {
+				want++
+			}
+			if rank, found := m.Rank(k); rank != want || found {
+				t.Errorf("Rank(%d): got=%d,%t want=%d,false", k, rank, found, want)
+				return false
+			}
+		}
+		for _, i := range []int{-1, len(keys)} {
+			if _, _, found := m.Select(i); found {
+				t.Errorf("Select(%d): found an entry out of bounds", i)
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMapClone(t *testing.T) {
+	m := NewMap[int, int](compare.Function[int])
+	for i := 0; i < 100; i++ {
+		m.Insert(i, -i)
+	}
+
+	c := m.Clone()
+	if c.leaf == m.leaf || c.bbleaf == m.bbleaf {
+		t.Fatal("the cloned map shares the leaf sentinels of the original map")
+	}
+	for i := 0; i < 100; i += 2 {
+		c.Delete(i)
+	}
+	c.Insert(1000, 0)
+	m.Insert(1, 1)
+
+	m.checkInvariants()
+	c.checkInvariants()
+
+	if n := m.Len(); n != 100 {
+		t.Errorf("wrong length of original map: got=%d want=100", n)
+	}
+	if n := c.Len(); n != 51 {
+		t.Errorf("wrong length of cloned map: got=%d want=51", n)
+	}
+	for i := 0; i < 100; i++ {
+		want := -i
+		if i == 1 {
+			want = 1
+		}
+		if v, ok := m.Lookup(i); !ok || v != want {
+			t.Errorf("wrong value of original map for key=%d: got=%d,%t want=%d,true", i, v, ok, want)
+		}
+		v, ok := c.Lookup(i)
+		if ok != (i%2 != 0) || (ok && v != -i) {
+			t.Errorf("wrong value of cloned map for key=%d: got=%d,%t", i, v, ok)
+		}
+	}
+	if _, ok := m.Lookup(1000); ok {
+		t.Error("key inserted in the clone found in the original map")
+	}
+
+	if c := new(Map[int, int]).Clone(); c.Len() != 0 {
+		t.Errorf("wrong length of cloned empty map: got=%d want=0", c.Len())
+	}
+}
+
+func TestMapSnapshot(t *testing.T) {
+	const N = 200
+	m := NewMap[int, int](compare.Function[int])
+	for i := 0; i < N; i++ {
+		m.Insert(i, -i)
+	}
+
+	s := m.Snapshot()
+	prng := rand.New(rand.NewSource(0))
+	for i := 0; i < 10*N; i++ {
+		k := prng.Intn(2 * N)
+		switch prng.Intn(4) {
+		case 0:
+			m.Insert(k, k)
+		case 1:
+			m.Delete(k)
+		case 2:
+			m.CompareAndSwap(k, -k, k, func(a, b int) bool { return a == b })
+		case 3:
+			Increment(m, k, 1)
+		}
+	}
+	m.DeleteMin()
+	m.DeleteMax()
+	m.Apply(func(k, v int) int { return v + 1 })
+	m.TrimBelow(N / 2)
+
+	// Writes to the snapshot must not be visible in the map either.
+	s2 := s.Snapshot()
+	for i := 0; i < N; i += 2 {
+		s2.Delete(i)
+	}
+
+	m.checkInvariants()
+	s.checkInvariants()
+	s2.checkInvariants()
+
+	if n := s.Len(); n != N {
+		t.Errorf("wrong length of snapshot: got=%d want=%d", n, N)
+	}
+	for i := 0; i < N; i++ {
+		if v, ok := s.Lookup(i); !ok || v != -i {
+			t.Errorf("wrong value of snapshot for key=%d: got=%d,%t want=%d,true", i, v, ok, -i)
+		}
+		v, ok := s2.Lookup(i)
+		if ok != (i%2 != 0) || (ok && v != -i) {
+			t.Errorf("wrong value of second snapshot for key=%d: got=%d,%t", i, v, ok)
+		}
+	}
+	if k, _, ok := m.Min(); ok && k < N/2 {
+		t.Errorf("wrong minimum key of map: got=%d want>=%d", k, N/2)
+	}
+
+	if s := new(Map[int, int]).Snapshot(); s.Len() != 0 {
+		t.Errorf("wrong length of snapshot of empty map: got=%d want=0", s.Len())
+	}
+}
+
+func TestMapClear(t *testing.T) {
+	m := NewMapStrict[int, int](compare.Function[int], func(a, b int) bool { return a == b })
+	for i := 0; i < 100; i++ {
+		m.Insert(i, -i)
+	}
+	version := m.Version()
+	leaf, bbleaf := m.leaf, m.bbleaf
+
+	m.Clear()
+	m.checkInvariants()
+
+	if m.leaf != leaf || m.bbleaf != bbleaf {
+		t.Error("leaf sentinels not retained after clearing the map")
+	}
+
+	if n := m.Len(); n != 0 {
+		t.Errorf("wrong length of cleared map: got=%d want=0", n)
+	}
+	for i := 0; i < 100; i++ {
+		if _, ok := m.Lookup(i); ok {
+			t.Errorf("key found in cleared map: %d", i)
+		}
+	}
+	m.Range(math.MinInt, func(k, _ int) bool {
+		t.Errorf("Range called f with key=%d on a cleared map", k)
+		return true
+	})
+
+	for i := 0; i < 50; i++ {
+		m.Insert(2*i, i)
+	}
+	m.checkInvariants()
+
+	if n := m.Len(); n != 50 {
+		t.Errorf("wrong length after reinserting keys: got=%d want=50", n)
+	}
+	if v, ok := m.Lookup(42); !ok || v != 21 {
+		t.Errorf("wrong value for key=42: got=%d,%t want=21,true", v, ok)
+	}
+	if m.Version() <= version {
+		t.Errorf("version did not increase after clearing the map: got=%d want>%d", m.Version(), version)
+	}
+	if m.equal == nil {
+		t.Error("equal function not retained after clearing the map")
+	}
+
+	new(Map[int, int]).Clear()
+}
+
+func TestMapInsertWith(t *testing.T) {
+	m := NewMap[int, int](compare.Function[int])
+	histogram := map[int]int{}
+	prng := rand.New(rand.NewSource(0))
+
+	for i := 0; i < 10000; i++ {
+		k := prng.Intn(100)
+		histogram[k]++
+
+		v := m.InsertWith(k, func(old int, existed bool) int {
+			if existed != (old != 0) {
+				t.Errorf("wrong existed flag for key=%d with value=%d: %t", k, old, existed)
+			}
+			return old + 1
+		})
+		if v != histogram[k] {
+			t.Errorf("wrong value returned for key=%d: got=%d want=%d", k, v, histogram[k])
+		}
+	}
+	m.checkInvariants()
+
+	if m.Len() != len(histogram) {
+		t.Errorf("wrong map length: got=%d want=%d", m.Len(), len(histogram))
+	}
+	for k, want := range histogram {
+		if v, ok := m.Lookup(k); !ok || v != want {
+			t.Errorf("wrong count for key=%d: got=%d,%t want=%d,true", k, v, ok, want)
+		}
+	}
+}
+
+func TestMapLookupOrInsert(t *testing.T) {
+	m := NewMap[string, int](compare.Function[string])
+
+	for i := 0; i < 10; i++ {
+		actual, loaded := m.LookupOrInsert("key", i)
+		if actual != 0 {
+			t.Errorf("wrong value returned: got=%d want=0", actual)
+		}
+		if loaded != (i != 0) {
+			t.Errorf("wrong loaded flag at call %d: got=%t want=%t", i, loaded, i != 0)
+		}
+		if n := m.Len(); n != 1 {
+			t.Errorf("wrong map length: got=%d want=1", n)
+		}
+	}
+
+	version := m.Version()
+	m.LookupOrInsert("key", 42)
+	m.RangeChangedSince(version, func(k string, _ int) bool {
+		t.Errorf("loading an existing key marked it as changed: %q", k)
+		return true
+	})
+
+	for i, k := range []string{"a", "b", "c", "d", "e"} {
+		if actual, loaded := m.LookupOrInsert(k, i); loaded || actual != i {
+			t.Errorf("wrong result inserting key=%q: got=%d,%t want=%d,false", k, actual, loaded, i)
+		}
+	}
+	m.checkInvariants()
+
+	if n := m.Len(); n != 6 {
+		t.Errorf("wrong map length: got=%d want=6", n)
+	}
+	if v, _ := m.Lookup("key"); v != 0 {
+		t.Errorf("stored value was modified: got=%d want=0", v)
+	}
+}
+
+func TestMapMerge(t *testing.T) {
+	sum := func(_ int, a, b int) int { return a + b }
+
+	tests := []struct {
+		scenario string
+		a, b     []int
+		resolve  func(int, int, int) int
+		want     string
+	}{
+		{
+			scenario: "disjoint",
+			a:        []int{1, 2, 3},
+			b:        []int{4, 5},
+			want:     "map[1:1 2:2 3:3 4:40 5:50]",
+		},
+		{
+			scenario: "overlapping with nil resolve",
+			a:        []int{1, 2, 3},
+			b:        []int{2, 3, 4},
+			want:     "map[1:1 2:20 3:30 4:40]",
+		},
+		{
+			scenario: "overlapping with resolve",
+			a:        []int{1, 2, 3},
+			b:        []int{2, 3, 4},
+			resolve:  sum,
+			want:     "map[1:1 2:22 3:33 4:40]",
+		},
+		{
+			scenario: "empty other",
+			a:        []int{1},
+			want:     "map[1:1]",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			a := NewMap[int, int](compare.Function[int])
+			for _, k := range test.a {
+				a.Insert(k, k)
+			}
+			b := NewMap[int, int](compare.Function[int])
+			for _, k := range test.b {
+				b.Insert(k, 10*k)
+			}
+
+			a.Merge(b, test.resolve)
+			a.checkInvariants()
+			b.checkInvariants()
+
+			got := map[int]int{}
+			a.Range(math.MinInt, func(k, v int) bool {
+				got[k] = v
+				return true
+			})
+			if fmt.Sprint(got) != test.want {
+				t.Errorf("wrong entries after merge: got=%v want=%s", got, test.want)
+			}
+			if a.Len() != len(got) {
+				t.Errorf("wrong length after merge: got=%d want=%d", a.Len(), len(got))
+			}
+			if b.Len() != len(test.b) {
+				t.Errorf("other map was modified: got=%d entries want=%d", b.Len(), len(test.b))
+			}
+		})
+	}
+}
+
+func TestMapDeleteMinMax(t *testing.T) {
+	f := func(entries map[int32]int32) bool {
+		m := NewMap[int32, int32](compare.Function[int32])
+		for k, v := range entries {
+			m.Insert(k, v)
+		}
+
+		prev, n := int32(0), 0
+		for {
+			key, value, deleted := m.DeleteMin()
+			if !deleted {
+				break
+			}
+			m.checkInvariants()
+			if n > 0 && key <= prev {
+				t.Errorf("keys popped out of order: %d after %d", key, prev)
+				return false
+			}
+			if want := entries[key]; value != want {
+				t.Errorf("wrong value for key=%d: got=%d want=%d", key, value, want)
+				return false
+			}
+			prev = key
+			n++
+		}
+		if n != len(entries) || m.Len() != 0 {
+			t.Errorf("wrong number of entries popped: got=%d want=%d (len=%d)", n, len(entries), m.Len())
+			return false
+		}
+
+		for k, v := range entries {
+			m.Insert(k, v)
+		}
+		n = 0
+		for {
+			key, value, deleted := m.DeleteMax()
+			if !deleted {
+				break
+			}
+			m.checkInvariants()
+			if n > 0 && key >= prev {
+				t.Errorf("keys popped out of order: %d after %d", key, prev)
+				return false
+			}
+			if want := entries[key]; value != want {
+				t.Errorf("wrong value for key=%d: got=%d want=%d", key, value, want)
+				return false
+			}
+			prev = key
+			n++
+		}
+		if n != len(entries) || m.Len() != 0 {
+			t.Errorf("wrong number of entries popped: got=%d want=%d (len=%d)", n, len(entries), m.Len())
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+
+	var empty Map[int, int]
+	if _, _, deleted := empty.DeleteMin(); deleted {
+		t.Error("DeleteMin removed an entry from an empty map")
+	}
+	if _, _, deleted := empty.DeleteMax(); deleted {
+		t.Error("DeleteMax removed an entry from an empty map")
+	}
+}
+
+func TestMapEqual(t *testing.T) {
+	eq := func(a, b int) bool { return a == b }
+
+	ascending := NewMap[int, int](compare.Function[int])
+	descending := NewMap[int, int](compare.Function[int])
+	for i := 0; i < 100; i++ {
+		ascending.Insert(i, -i)
+		descending.Insert(99-i, i-99)
+	}
+
+	tests := []struct {
+		scenario string
+		other    func() *Map[int, int]
+		equal    bool
+	}{
+		{
+			scenario: "same entries inserted in a different order",
+			other:    func() *Map[int, int] { return descending },
+			equal:    true,
+		},
+		{
+			scenario: "one value differs",
+			other: func() *Map[int, int] {
+				c := descending.Clone()
+				c.Insert(42, 42)
+				return c
+			},
+			equal: false,
+		},
+		{
+			scenario: "one key differs",
+			other: func() *Map[int, int] {
+				c := descending.Clone()
+				c.Delete(42)
+				c.Insert(1000, -42)
+				return c
+			},
+			equal: false,
+		},
+		{
+			scenario: "different lengths",
+			other: func() *Map[int, int] {
+				c := descending.Clone()
+				c.Delete(42)
+				return c
+			},
+			equal: false,
+		},
+		{
+			scenario: "empty map",
+			other:    func() *Map[int, int] { return new(Map[int, int]) },
+			equal:    false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			other := test.other()
+			if equal := ascending.Equal(other, eq); equal != test.equal {
+				t.Errorf("wrong result: got=%t want=%t", equal, test.equal)
+			}
+			if equal := other.Equal(ascending, eq); equal != test.equal {
+				t.Errorf("wrong result of symmetric comparison: got=%t want=%t", equal, test.equal)
+			}
+		})
+	}
+
+	if !new(Map[int, int]).Equal(NewMap[int, int](compare.Function[int]), eq) {
+		t.Error("empty maps are not equal")
+	}
+}
+
+func TestMapBuildSorted(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 3, 7, 8, 9, 100, 1000, 1023, 1024} {
+		t.Run(fmt.Sprint(n), func(t *testing.T) {
+			entries := make([]Entry[int, int], n)
+			for i := range entries {
+				entries[i] = Entry[int, int]{Key: i + 1, Value: -(i + 1)}
+			}
+
+			m := NewMap[int, int](compare.Function[int])
+			m.Insert(-1, 0) // replaced by the build
+			m.BuildSorted(entries)
+			m.checkInvariants()
+
+			if m.Len() != n {
+				t.Errorf("wrong map length: got=%d want=%d", m.Len(), n)
+			}
+			for i := 1; i <= n; i++ {
+				if v, ok := m.Lookup(i); !ok || v != -i {
+					t.Errorf("wrong value for key=%d: got=%d,%t want=%d,true", i, v, ok, -i)
+				}
+			}
+			if _, ok := m.Lookup(-1); ok {
+				t.Error("entry inserted before the build found in the map")
+			}
+
+			m.Insert(0, 0)
+			m.Delete(n / 2)
+			m.checkInvariants()
+		})
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("building from unsorted entries did not panic")
+		}
+	}()
+	NewMap[int, int](compare.Function[int]).BuildSorted([]Entry[int, int]{{Key: 2}, {Key: 1}})
+}
+
+func TestMapCompareAndSwap(t *testing.T) {
+	m := NewMap[int, int](compare.Function[int])
+	equal := func(a, b int) bool { return a == b }
+	m.Insert(1, 10)
+
+	if m.CompareAndSwap(1, 11, 12, equal) {
+		t.Error("value swapped while the old value did not match")
+	}
+	if m.CompareAndSwap(2, 0, 12, equal) {
+		t.Error("value swapped for a key which did not exist")
+	}
+	if !m.CompareAndSwap(1, 10, 12, equal) {
+		t.Error("value not swapped while the old value matched")
+	}
+	if v, _ := m.Lookup(1); v != 12 {
+		t.Errorf("wrong value after swap: got=%d want=12", v)
+	}
+}
+
+func TestMapCompareAndDelete(t *testing.T) {
+	m := NewMap[int, int](compare.Function[int])
+	equal := func(a, b int) bool { return a == b }
+	for i := 0; i < 100; i++ {
+		m.Insert(i, i)
+	}
+
+	for i := 0; i < 100; i++ {
+		if m.CompareAndDelete(i, i+1, equal) {
+			t.Errorf("key=%d deleted while the old value did not match", i)
+		}
+	}
+	m.checkInvariants()
+
+	for i := 0; i < 100; i += 2 {
+		if !m.CompareAndDelete(i, i, equal) {
+			t.Errorf("key=%d not deleted while the old value matched", i)
+		}
+	}
+	m.checkInvariants()
+
+	if n := m.Len(); n != 50 {
+		t.Errorf("wrong number of entries in map: got=%d want=50", n)
+	}
+	for i := 0; i < 100; i++ {
+		if _, found := m.Lookup(i); found != (i%2 != 0) {
+			t.Errorf("wrong lookup result for key=%d: got=%t", i, found)
+		}
+	}
+}
+
+func TestMapWriteDOT(t *testing.T) {
+	m := NewMap[int, int](compare.Function[int])
+	m.Insert(2, 0)
+	m.Insert(1, 0)
+	m.Insert(3, 0)
+
+	b := new(strings.Builder)
+	if err := m.WriteDOT(b); err != nil {
+		t.Fatal(err)
+	}
+
+	const want = `digraph {
+	node [style=filled, fontcolor=white];
+	n0 [label="2", fillcolor=black];
+	n1 [label="1", fillcolor=red];
+	n0 -> n1;
+	n2 [label="3", fillcolor=red];
+	n0 -> n2;
+}
+`
+	if got := b.String(); got != want {
+		t.Errorf("wrong DOT output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestMapIsDense(t *testing.T) {
+	m := NewMap[int, int](compare.Function[int])
+	for _, k := range []int{1, 2, 3, 4, 6, 7, 8} {
+		m.Insert(k, k)
+	}
+	next := func(k int) int { return k + 1 }
+
+	tests := []struct {
+		lo, hi int
+		dense  bool
+	}{
+		{lo: 1, hi: 4, dense: true},
+		{lo: 2, hi: 3, dense: true},
+		{lo: 6, hi: 8, dense: true},
+		{lo: 4, hi: 4, dense: true},
+		{lo: 1, hi: 6, dense: false},
+		{lo: 0, hi: 2, dense: false},
+		{lo: 7, hi: 9, dense: false},
+		{lo: 5, hi: 5, dense: false},
+		{lo: 3, hi: 2, dense: true},
+	}
+
+	for _, test := range tests {
+		if dense := m.IsDense(test.lo, test.hi, next); dense != test.dense {
+			t.Errorf("IsDense(%d, %d): got=%t want=%t", test.lo, test.hi, dense, test.dense)
+		}
+	}
+}
+
+func TestMapSplit(t *testing.T) {
+	m := NewMap[int, int](compare.Function[int])
+	for i := 0; i < 100; i++ {
+		m.Insert(i, -i)
+	}
+
+	for _, pivot := range []int{-1, 0, 1, 50, 99, 100} {
+		left, right := m.Split(pivot)
+		left.checkInvariants()
+		right.checkInvariants()
+
+		if n := m.Len(); n != 100 {
+			t.Fatalf("splitting modified the map: len=%d", n)
+		}
+
+		want := compare.Clamp(pivot, 0, 100)
+		if n := left.Len(); n != want {
+			t.Errorf("pivot %d: wrong number of entries in left map: got=%d want=%d", pivot, n, want)
+		}
+		if n := right.Len(); n != 100-want {
+			t.Errorf("pivot %d: wrong number of entries in right map: got=%d want=%d", pivot, n, 100-want)
+		}
+
+		for i := 0; i < 100; i++ {
+			l, inLeft := left.Lookup(i)
+			r, inRight := right.Lookup(i)
+			switch {
+			case i < pivot && (!inLeft || inRight || l != -i):
+				t.Errorf("pivot %d: key=%d not found in left map", pivot, i)
+			case i >= pivot && (inLeft || !inRight || r != -i):
+				t.Errorf("pivot %d: key=%d not found in right map", pivot, i)
+			}
+		}
+
+		// The maps must remain usable after the split, and share no mutable
+		// state with the original map.
+		left.Insert(1000, 0)
+		right.Insert(-1000, 0)
+		left.Delete(pivot - 1)
+		right.Delete(pivot)
+		m.Insert(50, 1)
+		left.checkInvariants()
+		right.checkInvariants()
+		m.checkInvariants()
+		m.Insert(50, -50)
+
+		for i := 0; i < 100; i++ {
+			if v, ok := m.Lookup(i); !ok || v != -i {
+				t.Fatalf("pivot %d: modifying the split maps changed key=%d: got=%d,%t", pivot, i, v, ok)
+			}
+		}
+		for _, half := range [2]*Map[int, int]{left, right} {
+			if v, ok := half.Lookup(50); ok && v != -50 {
+				t.Errorf("pivot %d: modifying the original map changed a split map: got=%d want=-50", pivot, v)
+			}
+		}
+	}
+}
+
+func TestMapSplitRandom(t *testing.T) {
+	prng := rand.New(rand.NewSource(0))
+	for _, size := range []int{0, 1, 2, 3, 10, 100, 1000, 10000} {
+		m := NewMap[int, int](compare.Function[int])
+		for _, k := range prng.Perm(size) {
+			m.Insert(2*k, k)
+		}
+
+		for i := 0; i < 20; i++ {
+			pivot := prng.Intn(2*size + 2)
+			left, right := m.Split(pivot)
+			left.checkInvariants()
+			right.checkInvariants()
+
+			want := compare.Clamp((pivot+1)/2, 0, size)
+			if n := left.Len(); n != want {
+				t.Fatalf("size %d pivot %d: wrong number of entries in left map: got=%d want=%d", size, pivot, n, want)
+			}
+			if n := right.Len(); n != size-want {
+				t.Fatalf("size %d pivot %d: wrong number of entries in right map: got=%d want=%d", size, pivot, n, size-want)
+			}
+			if keys := append(left.Keys(), right.Keys()...); fmt.Sprint(keys) != fmt.Sprint(m.Keys()) {
+				t.Fatalf("size %d pivot %d: wrong keys in split maps", size, pivot)
+			}
+
+			// Only the nodes on the search path of the pivot are copied, the
+			// others are shared with the original map.
+			copied := 0
+			for _, half := range [2]*Map[int, int]{left, right} {
+				half.rangeNodes(half.root, func(n *node[int, int]) {
+					if n.epoch == left.epoch || n.epoch == right.epoch {
+						copied++
+					}
+				})
+			}
+			if limit := 3*m.Height() + 1; copied > limit {
+				t.Errorf("size %d pivot %d: too many nodes copied: got=%d want<=%d", size, pivot, copied, limit)
+			}
+		}
+	}
+}
+
+func TestJoin(t *testing.T) {
+	m := NewMap[int, int](compare.Function[int])
+	for i := 0; i < 100; i++ {
+		m.Insert(i, -i)
+	}
+
+	for _, pivot := range []int{0, 1, 50, 100} {
+		left, right := m.Split(pivot)
+
+		joined := Join(left, right)
+		joined.checkInvariants()
+
+		if n := joined.Len(); n != 100 {
+			t.Errorf("pivot %d: wrong number of entries in joined map: got=%d want=100", pivot, n)
+		}
+		for i := 0; i < 100; i++ {
+			if v, found := joined.Lookup(i); !found || v != -i {
+				t.Errorf("pivot %d: key=%d not found in joined map", pivot, i)
+			}
+		}
+
+		if pivot > 0 && pivot < 100 {
+			func() {
+				defer func() {
+					if recover() == nil {
+						t.Errorf("pivot %d: joining overlapping maps did not panic", pivot)
+					}
+				}()
+				Join(right, left)
+			}()
+		}
+	}
+
+	if n := Join(new(Map[int, int]), m).Len(); n != 100 {
+		t.Errorf("wrong number of entries when joining with an uninitialized map: got=%d want=100", n)
+	}
+}
+
+func TestJoinRandom(t *testing.T) {
+	prng := rand.New(rand.NewSource(0))
+	for _, size := range []int{1, 2, 3, 10, 100, 1000, 10000} {
+		m := NewMap[int, int](compare.Function[int])
+		for _, k := range prng.Perm(size) {
+			m.Insert(k, -k)
+		}
+		keys := fmt.Sprint(m.Keys())
+
+		for i := 0; i < 20; i++ {
+			pivot := prng.Intn(size + 1)
+			left, right := m.Split(pivot)
+			if i%2 != 0 {
+				// Unrelated maps do not share the leaf sentinels, the
+				// smallest one is copied.
+				right = NewMap[int, int](compare.Function[int])
+				m.Range(pivot, func(k, v int) bool { right.Insert(k, v); return true })
+			}
+
+			joined := Join(left, right)
+			joined.checkInvariants()
+			if n := joined.Len(); n != size {
+				t.Fatalf("size %d pivot %d: wrong number of entries in joined map: got=%d want=%d", size, pivot, n, size)
+			}
+			if got := fmt.Sprint(joined.Keys()); got != keys {
+				t.Fatalf("size %d pivot %d: wrong keys in joined map", size, pivot)
+			}
+			if i%2 == 0 {
+				copied := 0
+				joined.rangeNodes(joined.root, func(n *node[int, int]) {
+					if n.epoch == joined.epoch {
+						copied++
+					}
+				})
+				if limit := 3*m.Height() + 1; copied > limit {
+					t.Errorf("size %d pivot %d: too many nodes copied: got=%d want<=%d", size, pivot, copied, limit)
+				}
+			}
+
+			// Modifying the joined map must not affect the maps it was
+			// joined from, and the other way around.
+			joined.Delete(pivot)
+			joined.Delete(pivot - 1)
+			joined.Insert(-1, 0)
+			left.Insert(pivot, 1)
+			right.Delete(pivot)
+			joined.checkInvariants()
+			left.checkInvariants()
+			right.checkInvariants()
+
+			want := size + 1 // pivot inserted in left
+			if pivot < size {
+				want-- // pivot deleted from right
+			}
+			if n := left.Len() + right.Len(); n != want {
+				t.Fatalf("size %d pivot %d: modifying the joined map changed the input maps: got=%d entries want=%d", size, pivot, n, want)
+			}
+			if _, ok := joined.Lookup(pivot); ok {
+				t.Fatalf("size %d pivot %d: modifying the input maps changed the joined map", size, pivot)
+			}
+		}
+	}
+}
+
+func TestMapRangeChangedSince(t *testing.T) {
+	m := NewMap[int, int](compare.Function[int])
+	prng := rand.New(rand.NewSource(1))
+
+	for round := 0; round < 20; round++ {
+		version := m.Version()
+		changed := make(map[int]bool)
+
+		for i := 0; i < 50; i++ {
+			k := prng.Intn(100)
+			switch prng.Intn(4) {
+			case 0:
+				m.Delete(k)
+				delete(changed, k)
+			case 1:
+				if v, found := m.Lookup(k); found {
+					m.CompareAndSwap(k, v, v+1, func(a, b int) bool { return a == b })
+					changed[k] = true
+				}
+			default:
+				m.Insert(k, i)
+				changed[k] = true
+			}
+		}
+		m.checkInvariants()
+
+		got := []int{}
+		m.RangeChangedSince(version, func(k, v int) bool {
+			got = append(got, k)
+			return true
+		})
+
+		want := []int{}
+		for k := range changed {
+			want = append(want, k)
+		}
+		sort.Ints(want)
+
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("round %d: wrong changed entries:\ngot:  %v\nwant: %v", round, got, want)
+		}
+	}
+}
+
+func TestMapApply(t *testing.T) {
+	m := NewMap[int, int](compare.Function[int])
+	for i := 0; i < 100; i++ {
+		m.Insert(i, i)
+	}
+	version := m.Version()
+
+	keys := []int{}
+	m.Apply(func(k, v int) int {
+		keys = append(keys, k)
+		return 2 * v
+	})
+	m.checkInvariants()
+
+	if len(keys) != 100 || !sort.IntsAreSorted(keys) {
+		t.Errorf("keys not presented in ascending order: %v", keys)
+	}
+	for i := 0; i < 100; i++ {
+		if v, _ := m.Lookup(i); v != 2*i {
+			t.Errorf("wrong value for key=%d: got=%d want=%d", i, v, 2*i)
+		}
+	}
+
+	changed := 0
+	m.RangeChangedSince(version, func(int, int) bool {
+		changed++
+		return true
+	})
+	if changed != 100 {
+		t.Errorf("wrong number of changed entries: got=%d want=100", changed)
+	}
+
+	new(Map[int, int]).Apply(func(k, v int) int {
+		t.Errorf("unexpected call to f on zero-value map: key=%d", k)
+		return v
+	})
+}
+
+func TestMapPopRange(t *testing.T) {
+	tests := []struct {
+		lo, hi int
+		popped int
+	}{
+		{lo: 10, hi: 19, popped: 10}, // small fraction, deleted one by one
+		{lo: 10, hi: 89, popped: 80}, // large fraction, tree is rebuilt
+		{lo: -10, hi: 200, popped: 100},
+		{lo: 50, hi: 50, popped: 1},
+		{lo: 60, hi: 50, popped: 0},
+	}
+
+	for _, test := range tests {
+		m := NewMap[int, int](compare.Function[int])
+		for i := 0; i < 100; i++ {
+			m.Insert(i, -i)
+		}
+		version := m.Version()
+
+		entries := m.PopRange(test.lo, test.hi)
+		m.checkInvariants()
+
+		if len(entries) != test.popped {
+			t.Errorf("[%d,%d]: wrong number of entries popped: got=%d want=%d", test.lo, test.hi, len(entries), test.popped)
+		}
+		for i, e := range entries {
+			if want := compare.Max(test.lo, 0) + i; e.Key != want || e.Value != -want {
+				t.Errorf("[%d,%d]: wrong entry at index %d: got=%v want=%d", test.lo, test.hi, i, e, want)
+			}
+		}
+
+		if n := m.Len(); n != 100-test.popped {
+			t.Errorf("[%d,%d]: wrong number of entries remaining: got=%d want=%d", test.lo, test.hi, n, 100-test.popped)
+		}
+		for i := 0; i < 100; i++ {
+			_, found := m.Lookup(i)
+			if popped := i >= test.lo && i <= test.hi; found == popped {
+				t.Errorf("[%d,%d]: wrong lookup result for key=%d: %t", test.lo, test.hi, i, found)
+			}
+		}
+
+		m.RangeChangedSince(version, func(k, v int) bool {
+			t.Errorf("[%d,%d]: remaining key=%d reported as changed", test.lo, test.hi, k)
+			return false
+		})
+
+		for i := 0; i < 100; i++ {
+			m.Insert(i, i)
+		}
+		m.checkInvariants()
+	}
+}
+
+func TestMapCut(t *testing.T) {
+	tests := []struct {
+		lo, hi int
+		cut    int
+	}{
+		{lo: 10, hi: 19, cut: 10},
+		{lo: 10, hi: 89, cut: 80},
+		{lo: -10, hi: 200, cut: 100},
+		{lo: 60, hi: 50, cut: 0},
+	}
+
+	for _, test := range tests {
+		m := NewMap[int, int](compare.Function[int])
+		for i := 0; i < 100; i++ {
+			m.Insert(i, -i)
+		}
+
+		c := m.Cut(test.lo, test.hi)
+		m.checkInvariants()
+		c.checkInvariants()
+
+		if n := c.Len(); n != test.cut {
+			t.Errorf("[%d,%d]: wrong number of entries cut: got=%d want=%d", test.lo, test.hi, n, test.cut)
+		}
+		if n := m.Len(); n != 100-test.cut {
+			t.Errorf("[%d,%d]: wrong number of entries remaining: got=%d want=%d", test.lo, test.hi, n, 100-test.cut)
+		}
+		for i := 0; i < 100; i++ {
+			_, inMap := m.Lookup(i)
+			v, inCut := c.Lookup(i)
+			if cut := i >= test.lo && i <= test.hi; inCut != cut || inMap == cut || (inCut && v != -i) {
+				t.Errorf("[%d,%d]: key=%d found in the wrong map", test.lo, test.hi, i)
+			}
+		}
+
+		// The new map must remain usable after the cut.
+		c.Insert(1000, 0)
+		c.checkInvariants()
+	}
+}
+
+func TestMapTrim(t *testing.T) {
+	tests := []struct {
+		below, above int
+		trimmed      int
+	}{
+		{below: 10, above: 89, trimmed: 20}, // small fraction, deleted one by one
+		{below: 80, above: 99, trimmed: 80}, // large fraction, tree is rebuilt
+		{below: 0, above: 10, trimmed: 89},
+		{below: -10, above: 200, trimmed: 0},
+		{below: 50, above: 40, trimmed: 100},
+	}
+
+	for _, test := range tests {
+		m := NewMap[int, int](compare.Function[int])
+		for i := 0; i < 100; i++ {
+			m.Insert(i, -i)
+		}
+
+		trimmed := m.TrimBelow(test.below)
+		m.checkInvariants()
+		trimmed += m.TrimAbove(test.above)
+		m.checkInvariants()
+
+		if trimmed != test.trimmed {
+			t.Errorf("(%d,%d): wrong number of entries trimmed: got=%d want=%d", test.below, test.above, trimmed, test.trimmed)
+		}
+		if n := m.Len(); n != 100-test.trimmed {
+			t.Errorf("(%d,%d): wrong number of entries remaining: got=%d want=%d", test.below, test.above, n, 100-test.trimmed)
+		}
+		for i := 0; i < 100; i++ {
+			_, found := m.Lookup(i)
+			if kept := i >= test.below && i <= test.above; found != kept {
+				t.Errorf("(%d,%d): wrong lookup result for key=%d: %t", test.below, test.above, i, found)
+			}
+		}
+
+		for i := 0; i < 100; i++ {
+			m.Insert(i, i)
+		}
+		m.checkInvariants()
+	}
+
+	var m Map[int, int]
+	if n := m.TrimBelow(0); n != 0 {
+		t.Errorf("wrong number of entries trimmed from zero-value map: got=%d want=0", n)
+	}
+}
+
+func TestMapDeletePrefix(t *testing.T) {
+	hasPrefix := strings.HasPrefix
+	upperBound := func(prefix string) string {
+		return prefix[:len(prefix)-1] + string(prefix[len(prefix)-1]+1)
+	}
+
+	tests := []struct {
+		prefix  string
+		deleted int
+	}{
+		{prefix: "/a/", deleted: 3},
+		{prefix: "/a", deleted: 4},
+		{prefix: "/", deleted: 7},
+		{prefix: "/b/c", deleted: 1},
+		{prefix: "/z", deleted: 0},
+	}
+
+	for _, test := range tests {
+		m := NewMap[string, int](compare.Function[string])
+		keys := []string{"/a/1", "/a/2", "/a/b/c", "/aa", "/b", "/b/c", "/c", "a"}
+		for i, k := range keys {
+			m.Insert(k, i)
+		}
+
+		deleted := m.DeletePrefix(test.prefix, hasPrefix, upperBound)
+		m.checkInvariants()
+
+		if deleted != test.deleted {
+			t.Errorf("%q: wrong number of entries deleted: got=%d want=%d", test.prefix, deleted, test.deleted)
+		}
+		for _, k := range keys {
+			if _, found := m.Lookup(k); found == strings.HasPrefix(k, test.prefix) {
+				t.Errorf("%q: wrong lookup result for key=%q: %t", test.prefix, k, found)
+			}
+		}
+	}
+}
+
+func TestMapComparisons(t *testing.T) {
+	const n = 1000
+	c := compare.Counting(compare.Function[int])
+	m := NewMap[int, int](c.Compare)
+	for i := 0; i < n; i++ {
+		m.Insert(i, i)
+	}
+
+	// The height of a red-black tree is at most 2*log2(n+1).
+	limit := uint64(2 * math.Log2(n+1))
+	for i := 0; i < n; i++ {
+		c.Reset()
+		m.Lookup(i)
+		if count := c.Count(); count > limit {
+			t.Errorf("too many comparisons looking up key=%d: got=%d limit=%d", i, count, limit)
+		}
+	}
+}
+
+func TestMapHeight(t *testing.T) {
+	m := NewMap[int, int](compare.Function[int])
+	if h, bh := m.Height(), m.BlackHeight(); h != 0 || bh != 0 {
+		t.Errorf("wrong height of empty map: got=%d,%d want=0,0", h, bh)
+	}
+
+	prng := rand.New(rand.NewSource(0))
+	for i := 1; i <= 5000; i++ {
+		m.Insert(prng.Int(), i)
+
+		if i%500 != 0 {
+			continue
+		}
+		n := m.Len()
+		h, bh := m.Height(), m.BlackHeight()
+		if limit := 2 * math.Log2(float64(n+1)); float64(h) > limit {
+			t.Errorf("tree of %d entries is too high: got=%d want<=%.2f", n, h, limit)
+		}
+		// Paths alternate red and black nodes at most, and no path can be
+		// shorter than the black height.
+		if bh > h || h > 2*bh {
+			t.Errorf("black height of tree of %d entries is inconsistent with its height: got=%d (height=%d)", n, bh, h)
+		}
+		if minSize := 1<<bh - 1; n < minSize {
+			t.Errorf("tree of %d entries has a black height of %d which requires at least %d entries", n, bh, minSize)
+		}
+	}
+}
+
+func TestMapQuickValidate(t *testing.T) {
+	newMap := func() *Map[int, int] {
+		m := NewMap[int, int](compare.Function[int])
+		for i := 0; i < 1000; i++ {
+			m.Insert(i, i)
+		}
+		return m
+	}
+
+	if err := new(Map[int, int]).QuickValidate(); err != nil {
+		t.Errorf("zero-value map reported as corrupted: %v", err)
+	}
+	if err := newMap().QuickValidate(); err != nil {
+		t.Errorf("valid map reported as corrupted: %v", err)
+	}
+
+	tests := []struct {
+		scenario string
+		corrupt  func(*Map[int, int])
+	}{
+		{
+			scenario: "red root",
+			corrupt:  func(m *Map[int, int]) { m.root.color = red },
+		},
+		{
+			scenario: "keys out of order",
+			corrupt:  func(m *Map[int, int]) { m.root.a.key, m.root.b.key = m.root.b.key, m.root.a.key },
+		},
+		{
+			scenario: "red node with a red child",
+			corrupt: func(m *Map[int, int]) {
+				m.rangeNodes(m.root, func(n *node[int, int]) { n.color = red })
+				m.root.color = black
+			},
+		},
+		{
+			scenario: "wrong length",
+			corrupt:  func(m *Map[int, int]) { m.len = 10 },
+		},
+	}
+
+	for _, test := range tests {
+		m := newMap()
+		test.corrupt(m)
+		if err := m.QuickValidate(); err == nil {
+			t.Errorf("%s: corruption not detected", test.scenario)
+		}
+	}
+}
+
+func TestMapMemoryUsage(t *testing.T) {
+	m := NewMap[int64, int64](compare.Function[int64])
+	empty := m.MemoryUsage()
+
+	m.Insert(0, 0)
+	entry := m.MemoryUsage() - empty
+	if entry <= 0 {
+		t.Fatalf("memory usage did not grow after inserting an entry: %d", entry)
+	}
+
+	for i := int64(1); i < 10; i++ {
+		m.Insert(i, i)
+	}
+	if size := m.MemoryUsage() - empty; size != 10*entry {
+		t.Errorf("wrong memory usage of map entries: got=%d want=%d", size, 10*entry)
+	}
+
+	for i := int64(0); i < 10; i++ {
+		m.Delete(i)
+	}
+	if size := m.MemoryUsage(); size != empty {
+		t.Errorf("wrong memory usage of empty map: got=%d want=%d", size, empty)
+	}
+}
+
+func (m *Map[K, V]) checkInvariants() {
+	if m.root.color != black {
+		panic("root must be black")
+	}
+	ys := make([]int, 0)
+	xs := &ys
+	m.check(m.root, 0, xs)
+	i := 1
+	for i < len(*xs) {
+		if (*xs)[i-1] != (*xs)[i] {
+			fmt.Println(xs)
+			panic("black height not same for all the leaves")
+		}
+		i++
+	}
+	if m.leaf.size != 0 || m.bbleaf.size != 0 {
+		panic("leaves must have a size of zero")
+	}
+	m.checkAugmentation(m.root)
+}
+
+func (m *Map[K, V]) checkAugmentation(n *node[K, V]) (maxVersion uint64) {
+	if n == m.leaf {
+		return 0
+	}
+	maxVersion = n.version
+	for _, child := range [2]*node[K, V]{n.a, n.b} {
+		if v := m.checkAugmentation(child); v > maxVersion {
+			maxVersion = v
+		}
+	}
+	if n.maxVersion != maxVersion {
+		panic(fmt.Sprintf("wrong max version of subtree: got=%d want=%d", n.maxVersion, maxVersion))
+	}
+	if size := 1 + m.subtreeSize(n.a) + m.subtreeSize(n.b); n.size != size {
+		panic(fmt.Sprintf("wrong size of subtree: got=%d want=%d", n.size, size))
+	}
+	return maxVersion
+}
+
+func (m *Map[K, V]) subtreeSize(n *node[K, V]) int {
+	if n == m.leaf {
+		return 0
 	}
+	return n.size
 }
 
 func (m *Map[K, V]) check(n *node[K, V], bh int, xs *[]int) {
-	if n == &m.leaf {
+	if n == m.leaf {
 		*xs = append(*xs, bh)
 		return
 	}
@@ -547,7 +2689,7 @@ func (m *Map[K, V]) check(n *node[K, V], bh int, xs *[]int) {
 }
 
 func (m *Map[K, V]) preorder(n *node[K, V], tab string) {
-	if n != &m.leaf {
+	if n != m.leaf {
 		fmt.Println(tab, n.key, "=>", n.value, n.color)
 		m.preorder(n.a, ":"+tab)
 		m.preorder(n.b, ":"+tab)
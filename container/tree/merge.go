@@ -0,0 +1,120 @@
+package tree
+
+import "container/heap"
+
+// MergeRange performs a k-way merge of the maps passed as arguments, calling f
+// for each key present in any of the maps, in ascending order according to the
+// cmp comparison function. The maps must all be ordered by a comparison
+// function equivalent to cmp. If f returns false, the iteration is stopped.
+//
+// When a key exists in several maps, f is called once for the key. If combine
+// is nil, the value from the first map holding the key (in the order the maps
+// were passed) is presented. Otherwise, the values are folded by calling
+// combine with the accumulated value and the value of each subsequent map, in
+// the order the maps were passed.
+//
+// The merge uses a heap of cursors positioned in each map, so the entries are
+// never materialized in memory. The maps must not be modified during the
+// iteration. Programs built with Go 1.23 or later can use MergeSeq to range
+// over the merged entries instead.
+//
+// Complexity: O(k log m) with k being the number of entries visited, and m the
+// number of maps
+func MergeRange[K, V any](cmp func(K, K) int, combine func(K, V, V) V, f func(K, V) bool, maps ...*Map[K, V]) {
+	h := &mergeHeap[K, V]{cmp: cmp, cursors: make([]*cursor[K, V], 0, len(maps))}
+	for i, m := range maps {
+		c := &cursor[K, V]{m: m, index: i}
+		if c.init(); c.node != nil {
+			h.cursors = append(h.cursors, c)
+		}
+	}
+	heap.Init(h)
+
+	for len(h.cursors) != 0 {
+		c := h.cursors[0]
+		key, value := c.node.key, c.node.value
+		h.advance()
+
+		for len(h.cursors) != 0 && cmp(key, h.cursors[0].node.key) == 0 {
+			if combine != nil {
+				value = combine(key, value, h.cursors[0].node.value)
+			}
+			h.advance()
+		}
+
+		if !f(key, value) {
+			break
+		}
+	}
+}
+
+// cursor is an in-order iterator over the nodes of a map, using an explicit
+// stack of the ancestors of the current node.
+type cursor[K, V any] struct {
+	m     *Map[K, V]
+	node  *node[K, V]
+	stack []*node[K, V]
+	index int
+}
+
+func (c *cursor[K, V]) init() {
+	if c.m.root != nil {
+		c.push(c.m.root)
+	}
+	c.next()
+}
+
+func (c *cursor[K, V]) push(n *node[K, V]) {
+	for n != c.m.leaf {
+		c.stack = append(c.stack, n)
+		n = n.a
+	}
+}
+
+func (c *cursor[K, V]) next() {
+	if len(c.stack) == 0 {
+		c.node = nil
+		return
+	}
+	c.node = c.stack[len(c.stack)-1]
+	c.stack = c.stack[:len(c.stack)-1]
+	c.push(c.node.b)
+}
+
+// mergeHeap is a min-heap of cursors ordered by the key of their current node,
+// then by the position of their map in the list of merged maps.
+type mergeHeap[K, V any] struct {
+	cmp     func(K, K) int
+	cursors []*cursor[K, V]
+}
+
+func (h *mergeHeap[K, V]) Len() int { return len(h.cursors) }
+
+func (h *mergeHeap[K, V]) Less(i, j int) bool {
+	c1, c2 := h.cursors[i], h.cursors[j]
+	if cmp := h.cmp(c1.node.key, c2.node.key); cmp != 0 {
+		return cmp < 0
+	}
+	return c1.index < c2.index
+}
+
+func (h *mergeHeap[K, V]) Swap(i, j int) { h.cursors[i], h.cursors[j] = h.cursors[j], h.cursors[i] }
+
+func (h *mergeHeap[K, V]) Push(x any) { h.cursors = append(h.cursors, x.(*cursor[K, V])) }
+
+func (h *mergeHeap[K, V]) Pop() any {
+	c := h.cursors[len(h.cursors)-1]
+	h.cursors = h.cursors[:len(h.cursors)-1]
+	return c
+}
+
+// advance moves the cursor at the top of the heap to its next node, removing it
+// from the heap when it reaches the end of its map.
+func (h *mergeHeap[K, V]) advance() {
+	c := h.cursors[0]
+	if c.next(); c.node != nil {
+		heap.Fix(h, 0)
+	} else {
+		heap.Pop(h)
+	}
+}
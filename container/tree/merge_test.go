@@ -0,0 +1,68 @@
+package tree
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/segmentio/datastructures/v2/compare"
+)
+
+func TestMergeRange(t *testing.T) {
+	m1 := NewMap[int, string](compare.Function[int])
+	m2 := NewMap[int, string](compare.Function[int])
+	m3 := NewMap[int, string](compare.Function[int])
+	empty := new(Map[int, string])
+
+	for i := 0; i < 10; i += 2 {
+		m1.Insert(i, "a")
+	}
+	for i := 0; i < 10; i += 3 {
+		m2.Insert(i, "b")
+	}
+	m3.Insert(5, "c")
+	m3.Insert(6, "c")
+
+	tests := []struct {
+		scenario string
+		combine  func(int, string, string) string
+		want     string
+	}{
+		{
+			scenario: "first map wins",
+			want:     "[0:a 2:a 3:b 4:a 5:c 6:a 8:a 9:b]",
+		},
+		{
+			scenario: "combine values",
+			combine:  func(_ int, v1, v2 string) string { return v1 + v2 },
+			want:     "[0:ab 2:a 3:b 4:a 5:c 6:abc 8:a 9:b]",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			got := []string{}
+			MergeRange(compare.Function[int], test.combine, func(k int, v string) bool {
+				got = append(got, fmt.Sprintf("%d:%s", k, v))
+				return true
+			}, m1, empty, m2, m3)
+
+			if s := fmt.Sprint(got); s != test.want {
+				t.Errorf("wrong merged entries: got=%s want=%s", s, test.want)
+			}
+		})
+	}
+
+	n := 0
+	MergeRange(compare.Function[int], nil, func(int, string) bool {
+		n++
+		return n < 3
+	}, m1, m2, m3)
+	if n != 3 {
+		t.Errorf("wrong number of calls after stopping: got=%d want=3", n)
+	}
+
+	MergeRange(compare.Function[int], nil, func(k int, _ string) bool {
+		t.Errorf("unexpected key=%d when merging no maps", k)
+		return true
+	})
+}
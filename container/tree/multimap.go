@@ -0,0 +1,107 @@
+package tree
+
+// MultiMap is an ordered map associating each key to a list of values, which
+// may be used for example to implement secondary indexes where multiple
+// records share the same key.
+//
+// The zero-value is a valid empty map which supports lookups and deletes, but
+// must be initialized prior to inserting any keys.
+type MultiMap[K, V any] struct {
+	impl Map[K, []V]
+	len  int
+}
+
+// NewMultiMap instantiates a new multimap using the given comparison function
+// to order the keys.
+func NewMultiMap[K, V any](cmp func(K, K) int) *MultiMap[K, V] {
+	m := new(MultiMap[K, V])
+	m.Init(cmp)
+	return m
+}
+
+// Init initializes (or re-initializes) the map. The comparison function passed
+// as argument will be used to order the keys.
+//
+// Complexity: O(1)
+func (m *MultiMap[K, V]) Init(cmp func(K, K) int) {
+	m.impl.Init(cmp)
+	m.len = 0
+}
+
+// Len returns the number of values held in the map, across all keys.
+//
+// Complexity: O(1)
+func (m *MultiMap[K, V]) Len() int { return m.len }
+
+// Insert appends a value to the list of values associated with key.
+//
+// Complexity: O(log n)
+func (m *MultiMap[K, V]) Insert(key K, value V) {
+	m.impl.InsertWith(key, func(values []V, _ bool) []V { return append(values, value) })
+	m.len++
+}
+
+// Lookup returns the values associated with key, in the order they were
+// inserted, or nil if the key does not exist in the map. The returned slice
+// is a copy which the program may retain or modify.
+//
+// Complexity: O(log n) + O(k) with k being the number of values returned
+func (m *MultiMap[K, V]) Lookup(key K) []V {
+	values, _ := m.impl.Lookup(key)
+	if len(values) == 0 {
+		return nil
+	}
+	return append([]V(nil), values...)
+}
+
+// Delete removes key and all the values associated with it from the map. The
+// method returns the values removed, and a boolean indicating whether the key
+// was found.
+//
+// Complexity: O(log n)
+func (m *MultiMap[K, V]) Delete(key K) (values []V, deleted bool) {
+	values, deleted = m.impl.Delete(key)
+	m.len -= len(values)
+	return values, deleted
+}
+
+// DeleteValue removes the first value associated with key which the eq
+// function reports as equal to value. The order of the other values is
+// preserved, and the key is removed from the map when its last value is
+// deleted. The method returns true if a value was deleted.
+//
+// Complexity: O(log n) + O(k) with k being the number of values of the key
+func (m *MultiMap[K, V]) DeleteValue(key K, value V, eq func(V, V) bool) (deleted bool) {
+	values, _ := m.impl.Lookup(key)
+	for i := range values {
+		if eq(values[i], value) {
+			if len(values) == 1 {
+				m.impl.Delete(key)
+			} else {
+				var zero V
+				copy(values[i:], values[i+1:])
+				values[len(values)-1] = zero
+				m.impl.Insert(key, values[:len(values)-1])
+			}
+			m.len--
+			return true
+		}
+	}
+	return false
+}
+
+// Range calls f for each key and value of the map with a key greater or equal
+// to min. Keys are presented in ascending order, and the values of each key in
+// the order they were inserted. If f returns false, the iteration is stopped.
+//
+// Complexity: O(log n) + O(k) with k being the number of calls to f
+func (m *MultiMap[K, V]) Range(min K, f func(K, V) bool) {
+	m.impl.Range(min, func(key K, values []V) bool {
+		for _, value := range values {
+			if !f(key, value) {
+				return false
+			}
+		}
+		return true
+	})
+}
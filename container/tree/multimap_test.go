@@ -0,0 +1,107 @@
+package tree
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/segmentio/datastructures/v2/compare"
+)
+
+func TestMultiMap(t *testing.T) {
+	const N = 1000
+	m := NewMultiMap[int, int](compare.Function[int])
+	for i := 0; i < N; i++ {
+		m.Insert(i%3, i)
+	}
+	m.Insert(-1, 42)
+	if n := m.Len(); n != N+1 {
+		t.Errorf("wrong map length: got=%d want=%d", n, N+1)
+	}
+
+	values := m.Lookup(1)
+	if len(values) != N/3 {
+		t.Fatalf("wrong number of values for key=1: got=%d want=%d", len(values), N/3)
+	}
+	for i, v := range values {
+		if want := 3*i + 1; v != want {
+			t.Errorf("wrong value at index %d of key=1: got=%d want=%d", i, v, want)
+		}
+	}
+	values[0] = -1
+	if v := m.Lookup(1)[0]; v != 1 {
+		t.Errorf("modifying the result of Lookup changed the map: got=%d want=1", v)
+	}
+	if values := m.Lookup(3); values != nil {
+		t.Errorf("wrong values for missing key=3: got=%v want=nil", values)
+	}
+
+	prevKey, prevValue, n := -1, 0, 0
+	m.Range(0, func(k, v int) bool {
+		if k < prevKey || (k == prevKey && v <= prevValue) {
+			t.Errorf("pairs out of order: (%d,%d) after (%d,%d)", k, v, prevKey, prevValue)
+		}
+		if v%3 != k {
+			t.Errorf("wrong value for key=%d: %d", k, v)
+		}
+		prevKey, prevValue = k, v
+		n++
+		return true
+	})
+	if n != N {
+		t.Errorf("wrong number of pairs in range: got=%d want=%d", n, N)
+	}
+
+	if values, ok := m.Delete(-1); !ok || fmt.Sprint(values) != "[42]" {
+		t.Errorf("wrong values deleted for key=-1: got=%v,%t want=[42],true", values, ok)
+	}
+	if _, ok := m.Delete(-1); ok {
+		t.Error("key deleted twice")
+	}
+	if n := m.Len(); n != N {
+		t.Errorf("wrong map length after delete: got=%d want=%d", n, N)
+	}
+}
+
+func TestMultiMapDeleteValue(t *testing.T) {
+	eq := func(a, b string) bool { return a == b }
+	m := NewMultiMap[int, string](compare.Function[int])
+	m.Insert(1, "a")
+	m.Insert(2, "x")
+	m.Insert(1, "b")
+	m.Insert(1, "a")
+	m.Insert(1, "c")
+
+	if m.DeleteValue(1, "z", eq) {
+		t.Error("missing value deleted")
+	}
+	if !m.DeleteValue(1, "a", eq) {
+		t.Error("value not deleted")
+	}
+	if values := fmt.Sprint(m.Lookup(1)); values != "[b a c]" {
+		t.Errorf("wrong values after deleting first match: got=%s want=[b a c]", values)
+	}
+	m.Insert(1, "d")
+	m.DeleteValue(1, "c", eq)
+	if values := fmt.Sprint(m.Lookup(1)); values != "[b a d]" {
+		t.Errorf("wrong values after interleaved insert and delete: got=%s want=[b a d]", values)
+	}
+
+	if !m.DeleteValue(2, "x", eq) {
+		t.Error("last value of key not deleted")
+	}
+	if m.impl.Len() != 1 {
+		t.Errorf("key without values retained in the map: got=%d keys want=1", m.impl.Len())
+	}
+	if n := m.Len(); n != 3 {
+		t.Errorf("wrong map length: got=%d want=3", n)
+	}
+
+	pairs := []string{}
+	m.Range(0, func(k int, v string) bool {
+		pairs = append(pairs, fmt.Sprintf("%d:%s", k, v))
+		return len(pairs) < 2
+	})
+	if fmt.Sprint(pairs) != "[1:b 1:a]" {
+		t.Errorf("wrong pairs in range: got=%v want=[1:b 1:a]", pairs)
+	}
+}
@@ -0,0 +1,32 @@
+package tree
+
+import "sync"
+
+// MapPool is a pool of maps, which programs creating and discarding many
+// short-lived maps can use to reduce allocations.
+//
+// Maps are cleared when returned to the pool, which retains their leaf
+// sentinels, so reusing a map from the pool does not allocate.
+//
+// The zero-value is a valid empty pool.
+type MapPool[K, V any] struct{ pool sync.Pool }
+
+// Get returns an empty map ordered by the cmp comparison function, either
+// taken from the pool or newly allocated.
+func (p *MapPool[K, V]) Get(cmp func(K, K) int) *Map[K, V] {
+	m, _ := p.pool.Get().(*Map[K, V])
+	if m == nil {
+		return NewMap[K, V](cmp)
+	}
+	m.cmp, m.equal = cmp, nil
+	return m
+}
+
+// Put clears m and adds it to the pool. The program must not use m after
+// calling Put, but snapshots taken of m remain valid.
+func (p *MapPool[K, V]) Put(m *Map[K, V]) {
+	if m.root != nil {
+		m.Clear()
+		p.pool.Put(m)
+	}
+}
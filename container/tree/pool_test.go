@@ -0,0 +1,56 @@
+package tree
+
+import (
+	"testing"
+
+	"github.com/segmentio/datastructures/v2/compare"
+)
+
+func TestMapPool(t *testing.T) {
+	var pool MapPool[int, int]
+	descending := func(a, b int) int { return compare.Function(b, a) }
+
+	for i := 0; i < 10; i++ {
+		m := pool.Get(compare.Function[int])
+		if n := m.Len(); n != 0 {
+			t.Fatalf("map from the pool is not empty: len=%d", n)
+		}
+		for k := 0; k < 100; k++ {
+			m.Insert(k, k)
+		}
+		m.checkInvariants()
+		s := m.Snapshot()
+		pool.Put(m)
+
+		if n := s.Len(); n != 100 {
+			t.Fatalf("wrong length of snapshot after returning the map to the pool: got=%d want=100", n)
+		}
+		s.checkInvariants()
+
+		m = pool.Get(descending)
+		m.Insert(1, 1)
+		m.Insert(2, 2)
+		if k, _, _ := m.Min(); k != 2 {
+			t.Fatalf("map from the pool does not use the new comparison function: min=%d want=2", k)
+		}
+		m.checkInvariants()
+		pool.Put(m)
+	}
+
+	// Uninitialized maps are not added to the pool.
+	pool.Put(new(Map[int, int]))
+	if m := pool.Get(compare.Function[int]); m.root == nil {
+		t.Error("uninitialized map returned by the pool")
+	}
+}
+
+func BenchmarkMapPool(b *testing.B) {
+	var pool MapPool[int, int]
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		m := pool.Get(compare.Function[int])
+		m.Insert(i, i)
+		pool.Put(m)
+	}
+}
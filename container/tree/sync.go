@@ -0,0 +1,106 @@
+package tree
+
+import "sync"
+
+// SyncMap is an ordered map safe to use concurrently from multiple goroutines.
+//
+// SyncMap wraps a Map guarded by a single read-write mutex: lookups and
+// iterations may run in parallel, while inserts and deletes are serialized.
+// Programs with write-heavy workloads on many goroutines may prefer
+// ConcurrentMap, which spreads keys across multiple independently locked maps.
+//
+// SyncMap values must be constructed by a call to NewSyncMap.
+type SyncMap[K, V any] struct {
+	mutex sync.RWMutex
+	impl  Map[K, V]
+}
+
+// NewSyncMap constructs a map ordered by the cmp comparison function.
+func NewSyncMap[K, V any](cmp func(K, K) int) *SyncMap[K, V] {
+	m := new(SyncMap[K, V])
+	m.impl.Init(cmp)
+	return m
+}
+
+// Len returns the number of entries in the map.
+//
+// Complexity: O(1)
+func (m *SyncMap[K, V]) Len() int {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.impl.Len()
+}
+
+// Insert inserts or replaces a value associated with a key in the map. The
+// method returns the previous value associated with the key and true if it
+// replaced one, or the zero-value and false otherwise.
+//
+// Complexity: O(log n)
+func (m *SyncMap[K, V]) Insert(key K, value V) (previous V, replaced bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.impl.Insert(key, value)
+}
+
+// Lookup returns the value associated with the given key in the map, and a
+// boolean value indicating whether the key was found in the map.
+//
+// Complexity: O(log n)
+func (m *SyncMap[K, V]) Lookup(key K) (value V, found bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.impl.Lookup(key)
+}
+
+// Search returns the entry found in the map where the key was less or equal to
+// the one passed as argument.
+//
+// Complexity: O(log n)
+func (m *SyncMap[K, V]) Search(key K) (matchKey K, matchValue V, found bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.impl.Search(key)
+}
+
+// Min returns the entry with the smallest key in the map.
+//
+// Complexity: O(log n)
+func (m *SyncMap[K, V]) Min() (key K, value V, found bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.impl.Min()
+}
+
+// Max returns the entry with the largest key in the map.
+//
+// Complexity: O(log n)
+func (m *SyncMap[K, V]) Max() (key K, value V, found bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.impl.Max()
+}
+
+// Delete deletes the entry associated with the key passed as argument,
+// returning the value it was associated with and true if the key existed in
+// the map, or the zero-value and false otherwise.
+//
+// Complexity: O(log n)
+func (m *SyncMap[K, V]) Delete(key K) (value V, deleted bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.impl.Delete(key)
+}
+
+// Range calls f for each entry of the map with a key greater or equal to min,
+// in ascending order. If f returns false, the iteration is stopped.
+//
+// The read lock is held for the whole iteration, which presents a consistent
+// view of the map but blocks writers until Range returns. The function f must
+// not call methods of the map, which may deadlock.
+//
+// Complexity: O(log n) + O(k) with k being the number of calls to f
+func (m *SyncMap[K, V]) Range(min K, f func(K, V) bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	m.impl.Range(min, f)
+}
@@ -0,0 +1,87 @@
+package tree
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/segmentio/datastructures/v2/compare"
+)
+
+func TestSyncMap(t *testing.T) {
+	m := NewSyncMap[int, int](compare.Function[int])
+	for i := 0; i < 100; i += 2 {
+		m.Insert(i, -i)
+	}
+	if n := m.Len(); n != 50 {
+		t.Errorf("wrong map length: got=%d want=50", n)
+	}
+	if v, ok := m.Lookup(42); !ok || v != -42 {
+		t.Errorf("wrong value for key=42: got=%d,%t want=-42,true", v, ok)
+	}
+	if k, v, ok := m.Search(43); !ok || k != 42 || v != -42 {
+		t.Errorf("wrong search result for key=43: got=%d,%d,%t want=42,-42,true", k, v, ok)
+	}
+	if k, _, ok := m.Min(); !ok || k != 0 {
+		t.Errorf("wrong min key: got=%d,%t want=0,true", k, ok)
+	}
+	if k, _, ok := m.Max(); !ok || k != 98 {
+		t.Errorf("wrong max key: got=%d,%t want=98,true", k, ok)
+	}
+	if v, ok := m.Delete(42); !ok || v != -42 {
+		t.Errorf("wrong deleted value for key=42: got=%d,%t want=-42,true", v, ok)
+	}
+	if _, ok := m.Lookup(42); ok {
+		t.Error("deleted key found in map")
+	}
+
+	n := 0
+	m.Range(90, func(k, v int) bool {
+		if want := 90 + 2*n; k != want {
+			t.Errorf("wrong key in range: got=%d want=%d", k, want)
+		}
+		n++
+		return true
+	})
+	if n != 5 {
+		t.Errorf("wrong number of keys in range: got=%d want=5", n)
+	}
+}
+
+func TestSyncMapParallel(t *testing.T) {
+	const N = 1000
+	m := NewSyncMap[int, int](compare.Function[int])
+	wg := sync.WaitGroup{}
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			prng := rand.New(rand.NewSource(int64(g)))
+			for i := g; i < N; i += 8 {
+				m.Insert(i, i)
+				m.Lookup(prng.Intn(N))
+				m.Search(prng.Intn(N))
+				m.Min()
+				m.Max()
+				if k := prng.Intn(N); k%2 != 0 {
+					m.Delete(k)
+				}
+			}
+			prev := -1
+			m.Range(0, func(k, v int) bool {
+				if k <= prev {
+					t.Errorf("keys out of order: %d after %d", k, prev)
+				}
+				prev = k
+				return true
+			})
+		}(g)
+	}
+	wg.Wait()
+
+	for i := 0; i < N; i += 2 {
+		if v, ok := m.Lookup(i); !ok || v != i {
+			t.Errorf("wrong value for key=%d: got=%d,%t want=%d,true", i, v, ok, i)
+		}
+	}
+}
@@ -11,6 +11,27 @@ func New[E any](cmp func(E, E) int) *Tree[E] {
 	return t
 }
 
+// FromSlice constructs a new tree containing the elements of the slice, using
+// the comparison function passed as first argument to order the elements.
+// Elements comparing equal are deduplicated, the first one is retained.
+//
+// Complexity: O(n) if the slice is sorted, O(n log n) otherwise
+func FromSlice[E any](cmp func(E, E) int, elems []E) *Tree[E] {
+	t := New(cmp)
+	sorted := true
+	for i := 1; i < len(elems) && sorted; i++ {
+		sorted = cmp(elems[i-1], elems[i]) < 0
+	}
+	if sorted {
+		t.impl.build(len(elems), func(i int) (E, struct{}) { return elems[i], struct{}{} })
+	} else {
+		for _, elem := range elems {
+			t.Insert(elem)
+		}
+	}
+	return t
+}
+
 // Init initializes the tree with the given comparison function to order the
 // elements.
 //
@@ -32,6 +53,31 @@ func (t *Tree[E]) Range(min E, f func(E) bool) {
 	t.impl.Range(min, func(elem E, _ struct{}) bool { return f(elem) })
 }
 
+// IntersectSorted calls f for each element of the tree which is also present
+// in the elems slice, in the order defined by the comparison function. The
+// slice must be sorted according to the comparison function of the tree. If f
+// returns false, the iteration is stopped.
+//
+// Complexity: O(log n) + O(n + m) with m being the length of the slice
+func (t *Tree[E]) IntersectSorted(elems []E, f func(E) bool) {
+	t.impl.IntersectSorted(elems, func(elem E, _ struct{}) bool { return f(elem) })
+}
+
+// ToSlice returns a slice containing the elements of the tree, in the order
+// defined by the comparison function.
+//
+// Complexity: O(n)
+func (t *Tree[E]) ToSlice() []E {
+	elems := make([]E, 0, t.Len())
+	if t.impl.root != nil {
+		t.impl.rangeFrom(t.impl.root, func(elem E, _ struct{}) bool {
+			elems = append(elems, elem)
+			return true
+		})
+	}
+	return elems
+}
+
 // Insert inserts a new element in the tree. The method panics if the tree
 // had not been initialized by a call to New or Init.
 //
@@ -81,3 +127,97 @@ func (t *Tree[E]) Max() (max E, found bool) {
 	max, _, found = t.impl.Max()
 	return max, found
 }
+
+// Rank returns the position of elem in the sorted order of the tree, starting
+// at zero, and whether elem exists in the tree. If elem does not exist, the
+// position is the one it would be inserted at.
+//
+// Complexity: O(log n)
+func (t *Tree[E]) Rank(elem E) (rank int, found bool) {
+	return t.impl.Rank(elem)
+}
+
+// Select returns the i-th smallest element of the tree, starting at zero. The
+// method returns found=false if i is negative or not less than the number of
+// elements in the tree.
+//
+// Complexity: O(log n)
+func (t *Tree[E]) Select(i int) (elem E, found bool) {
+	elem, _, found = t.impl.Select(i)
+	return elem, found
+}
+
+// Union returns a new tree containing the elements present in t or other.
+//
+// Both trees must be ordered by the same comparison function; since functions
+// cannot be compared in Go, this requirement is not verified and the result is
+// undefined if it is not met. The new tree uses the comparison function of t,
+// and neither t nor other are modified.
+//
+// Complexity: O(n + m) with m being the number of elements in other
+func (t *Tree[E]) Union(other *Tree[E]) *Tree[E] {
+	return t.combine(other, true, true, true)
+}
+
+// Intersect returns a new tree containing the elements present in both t and
+// other. The trees must be ordered by the same comparison function, see Union
+// for details.
+//
+// Complexity: O(n + m) with m being the number of elements in other
+func (t *Tree[E]) Intersect(other *Tree[E]) *Tree[E] {
+	return t.combine(other, false, true, false)
+}
+
+// Difference returns a new tree containing the elements of t which are not
+// present in other. The trees must be ordered by the same comparison function,
+// see Union for details.
+//
+// Complexity: O(n + m) with m being the number of elements in other
+func (t *Tree[E]) Difference(other *Tree[E]) *Tree[E] {
+	return t.combine(other, true, false, false)
+}
+
+// combine merges the elements of t and other in a single simultaneous ordered
+// walk of both trees, retaining the elements only in t, in both trees, or only
+// in other according to the boolean flags. Since the elements are produced in
+// order, the new tree is built without rebalancing.
+func (t *Tree[E]) combine(other *Tree[E], onlyT, both, onlyOther bool) *Tree[E] {
+	cmp := t.impl.cmp
+	if cmp == nil {
+		cmp = other.impl.cmp
+	}
+	if cmp == nil {
+		return new(Tree[E])
+	}
+
+	c1 := &cursor[E, struct{}]{m: &t.impl}
+	c2 := &cursor[E, struct{}]{m: &other.impl}
+	c1.init()
+	c2.init()
+
+	var elems []E
+	for c1.node != nil || c2.node != nil {
+		switch {
+		case c2.node == nil || (c1.node != nil && cmp(c1.node.key, c2.node.key) < 0):
+			if onlyT {
+				elems = append(elems, c1.node.key)
+			}
+			c1.next()
+		case c1.node == nil || cmp(c1.node.key, c2.node.key) > 0:
+			if onlyOther {
+				elems = append(elems, c2.node.key)
+			}
+			c2.next()
+		default:
+			if both {
+				elems = append(elems, c1.node.key)
+			}
+			c1.next()
+			c2.next()
+		}
+	}
+
+	r := New(cmp)
+	r.impl.build(len(elems), func(i int) (E, struct{}) { return elems[i], struct{}{} })
+	return r
+}
@@ -0,0 +1,229 @@
+package tree
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/segmentio/datastructures/v2/compare"
+)
+
+func TestTreeFromSliceSorted(t *testing.T) {
+	for n := 0; n <= 100; n++ {
+		elems := make([]int, n)
+		for i := range elems {
+			elems[i] = 2 * i
+		}
+
+		tree := FromSlice(compare.Function[int], elems)
+		tree.impl.checkInvariants()
+
+		if got := tree.ToSlice(); fmt.Sprint(got) != fmt.Sprint(elems) {
+			t.Fatalf("wrong elements in tree built from %d sorted elements: got=%v want=%v", n, got, elems)
+		}
+
+		// The tree must remain valid when modified after being built.
+		for i := range elems {
+			tree.Insert(2*i + 1)
+		}
+		tree.impl.checkInvariants()
+		for i := range elems {
+			tree.Delete(2 * i)
+		}
+		tree.impl.checkInvariants()
+
+		if n := tree.Len(); n != len(elems) {
+			t.Fatalf("wrong number of elements: got=%d want=%d", n, len(elems))
+		}
+	}
+}
+
+func TestTreeFromSliceUnsorted(t *testing.T) {
+	tree := FromSlice(compare.Function[int], []int{3, 1, 2, 3, 1, 0})
+	tree.impl.checkInvariants()
+
+	want := []int{0, 1, 2, 3}
+	if got := tree.ToSlice(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("wrong elements in tree: got=%v want=%v", got, want)
+	}
+}
+
+func TestTreeDescending(t *testing.T) {
+	tree := FromSlice(compare.Function[int], []int{1, 2, 3, 4, 5})
+	desc := tree.Descending()
+
+	if min, _ := desc.Min(); min != 5 {
+		t.Errorf("wrong min element of descending view: got=%d want=5", min)
+	}
+	if max, _ := desc.Max(); max != 1 {
+		t.Errorf("wrong max element of descending view: got=%d want=1", max)
+	}
+
+	elems := []int{}
+	desc.Range(3, func(elem int) bool {
+		elems = append(elems, elem)
+		return true
+	})
+	if fmt.Sprint(elems) != "[3 2 1]" {
+		t.Errorf("wrong elements in descending range: %v", elems)
+	}
+
+	tree.Insert(0)
+	elems = elems[:0]
+	desc.Range(10, func(elem int) bool {
+		elems = append(elems, elem)
+		return elem > 2
+	})
+	if fmt.Sprint(elems) != "[5 4 3 2]" {
+		t.Errorf("wrong elements in descending range: %v", elems)
+	}
+
+	if desc.Len() != 6 || !desc.Contains(0) || desc.Ascending() != tree {
+		t.Error("descending view does not reflect the tree")
+	}
+}
+
+func TestTreeRankSelect(t *testing.T) {
+	tree := FromSlice(compare.Function[int], []int{10, 20, 30, 40, 50})
+
+	tests := []struct {
+		elem  int
+		rank  int
+		found bool
+	}{
+		{elem: 10, rank: 0, found: true},
+		{elem: 30, rank: 2, found: true},
+		{elem: 50, rank: 4, found: true},
+		{elem: 5, rank: 0, found: false},
+		{elem: 35, rank: 3, found: false},
+		{elem: 60, rank: 5, found: false},
+	}
+
+	for _, test := range tests {
+		rank, found := tree.Rank(test.elem)
+		if rank != test.rank || found != test.found {
+			t.Errorf("Rank(%d): got=%d,%t want=%d,%t", test.elem, rank, found, test.rank, test.found)
+		}
+	}
+
+	for i := -1; i <= 5; i++ {
+		elem, found := tree.Select(i)
+		if want := i >= 0 && i < 5; found != want {
+			t.Errorf("Select(%d): wrong result: got=%t want=%t", i, found, want)
+		} else if found && elem != 10*(i+1) {
+			t.Errorf("Select(%d): wrong element: got=%d want=%d", i, elem, 10*(i+1))
+		}
+	}
+
+	if rank, found := new(Tree[int]).Rank(1); rank != 0 || found {
+		t.Errorf("Rank on zero-value tree: got=%d,%t want=0,false", rank, found)
+	}
+}
+
+func TestTreeSetAlgebra(t *testing.T) {
+	prng := rand.New(rand.NewSource(0))
+	randomSet := func() (*Tree[int], map[int]bool) {
+		tree, set := New(compare.Function[int]), map[int]bool{}
+		for i, n := 0, prng.Intn(100); i < n; i++ {
+			x := prng.Intn(100)
+			tree.Insert(x)
+			set[x] = true
+		}
+		return tree, set
+	}
+
+	operations := []struct {
+		scenario string
+		function func(t1, t2 *Tree[int]) *Tree[int]
+		contains func(in1, in2 bool) bool
+	}{
+		{
+			scenario: "union",
+			function: (*Tree[int]).Union,
+			contains: func(in1, in2 bool) bool { return in1 || in2 },
+		},
+		{
+			scenario: "intersect",
+			function: (*Tree[int]).Intersect,
+			contains: func(in1, in2 bool) bool { return in1 && in2 },
+		},
+		{
+			scenario: "difference",
+			function: (*Tree[int]).Difference,
+			contains: func(in1, in2 bool) bool { return in1 && !in2 },
+		},
+	}
+
+	for _, op := range operations {
+		t.Run(op.scenario, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				t1, s1 := randomSet()
+				t2, s2 := randomSet()
+				l1, l2 := t1.Len(), t2.Len()
+
+				want := []int{}
+				for x := 0; x < 100; x++ {
+					if op.contains(s1[x], s2[x]) {
+						want = append(want, x)
+					}
+				}
+
+				r := op.function(t1, t2)
+				r.impl.checkInvariants()
+				if got := r.ToSlice(); fmt.Sprint(got) != fmt.Sprint(want) {
+					t.Fatalf("wrong elements: got=%v want=%v", got, want)
+				}
+				if t1.Len() != l1 || t2.Len() != l2 {
+					t.Fatalf("input trees modified: got=%d,%d want=%d,%d", t1.Len(), t2.Len(), l1, l2)
+				}
+			}
+
+			empty := new(Tree[int])
+			if n := op.function(empty, empty).Len(); n != 0 {
+				t.Errorf("wrong length of result on empty trees: got=%d want=0", n)
+			}
+		})
+	}
+}
+
+func TestTreeRange(t *testing.T) {
+	tree := FromSlice(compare.Function[int], []int{-3, -1, 0, 2, 4, 6, 8})
+
+	tests := []struct {
+		min  int
+		want []int
+	}{
+		{min: -10, want: []int{-3, -1, 0, 2, 4, 6, 8}},
+		{min: 0, want: []int{0, 2, 4, 6, 8}},
+		{min: 3, want: []int{4, 6, 8}},
+		{min: 4, want: []int{4, 6, 8}},
+		{min: 8, want: []int{8}},
+		{min: 9, want: []int{}},
+	}
+
+	for _, test := range tests {
+		got := []int{}
+		tree.Range(test.min, func(elem int) bool {
+			got = append(got, elem)
+			return true
+		})
+		if fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("wrong elements in range from %d: got=%v want=%v", test.min, got, test.want)
+		}
+	}
+
+	// The zero-value of an unsigned type is the smallest element, ranging
+	// from it visits every element of the tree.
+	unsigned := FromSlice(compare.Function[uint], []uint{0, 1, 5, 10})
+	n := 0
+	unsigned.Range(0, func(uint) bool { n++; return true })
+	if n != unsigned.Len() {
+		t.Errorf("wrong number of elements in range from the zero-value: got=%d want=%d", n, unsigned.Len())
+	}
+
+	n = 0
+	tree.Range(-10, func(int) bool { n++; return n < 2 })
+	if n != 2 {
+		t.Errorf("range not stopped when the function returned false: got=%d calls want=2", n)
+	}
+}
@@ -0,0 +1,188 @@
+// Package hashring provides the implementation of a consistent hashing ring,
+// which distributes keys across a set of nodes while minimizing the number of
+// keys that move when nodes are added or removed.
+package hashring
+
+import (
+	"encoding/binary"
+	"hash/maphash"
+
+	"github.com/segmentio/datastructures/v2/compare"
+	"github.com/segmentio/datastructures/v2/container/tree"
+)
+
+// Ring is a consistent hashing ring mapping keys to nodes of type T.
+//
+// Each node is placed at a number of positions on the ring proportional to its
+// weight, and keys are assigned to the node at the first position following
+// the hash of the key. The positions of a node are derived from the bytes
+// returned by the key function of the ring, which must return distinct bytes
+// for distinct nodes, and the same bytes for nodes that compare equal.
+//
+// Hashes are computed with a random seed generated when the ring is first used,
+// so the assignment of keys to nodes is only consistent within a ring; it is
+// not preserved across rings or processes.
+//
+// The zero-value is a valid empty ring which supports lookups and removals, but
+// must be initialized prior to adding nodes. Ring instances are not safe to use
+// concurrently from multiple goroutines.
+type Ring[T comparable] struct {
+	hashseed  maphash.Seed
+	positions tree.Map[uint64, T]
+	weights   map[T]int
+	key       func(T) []byte
+}
+
+// NewRing instantiates a new ring placing nodes at positions derived from the
+// bytes returned by the key function.
+func NewRing[T comparable](key func(T) []byte) *Ring[T] {
+	r := new(Ring[T])
+	r.Init(key)
+	return r
+}
+
+// Init initializes (or re-initializes) the ring, removing all nodes. The key
+// function passed as argument returns the bytes identifying nodes on the ring.
+//
+// Complexity: O(1)
+func (r *Ring[T]) Init(key func(T) []byte) {
+	r.hashseed = maphash.MakeSeed()
+	r.positions.Init(compare.Function[uint64])
+	r.weights = make(map[T]int)
+	r.key = key
+}
+
+// Len returns the number of nodes on the ring.
+//
+// Complexity: O(1)
+func (r *Ring[T]) Len() int {
+	return len(r.weights)
+}
+
+// Weight returns the weight of node on the ring, or zero if the node is not
+// on the ring.
+//
+// Complexity: O(1)
+func (r *Ring[T]) Weight(node T) int {
+	return r.weights[node]
+}
+
+// Add places node on the ring at weight positions, replacing the previous
+// weight if the node was already on the ring. A weight of zero or less
+// removes the node.
+//
+// The ring must have been initialized by a call to NewRing or Init or the call
+// to Add will panic.
+//
+// Complexity: O(w log n) with w being the weight of the node, and n the total
+// number of positions on the ring
+func (r *Ring[T]) Add(node T, weight int) {
+	if r.key == nil {
+		panic("hashring: Add called on a ring which was not initialized")
+	}
+	r.Remove(node)
+	if weight <= 0 {
+		return
+	}
+	r.weights[node] = weight
+	r.rangePositions(node, weight, func(pos uint64) {
+		// Positions are taken by the first node hashed there; collisions of
+		// 64 bits hashes are unlikely enough to simply skip them.
+		if _, taken := r.positions.Lookup(pos); !taken {
+			r.positions.Insert(pos, node)
+		}
+	})
+}
+
+// Remove removes node from the ring. The method returns false if the node was
+// not on the ring.
+//
+// Complexity: O(w log n) with w being the weight of the node, and n the total
+// number of positions on the ring
+func (r *Ring[T]) Remove(node T) (removed bool) {
+	weight, ok := r.weights[node]
+	if !ok {
+		return false
+	}
+	delete(r.weights, node)
+	r.rangePositions(node, weight, func(pos uint64) {
+		if n, found := r.positions.Lookup(pos); found && n == node {
+			r.positions.Delete(pos)
+		}
+	})
+	return true
+}
+
+// Get returns the node that key is assigned to. If the ring is empty, the
+// zero-value of T is returned.
+//
+// Complexity: O(log n)
+func (r *Ring[T]) Get(key []byte) (node T) {
+	r.walk(key, func(n T) bool {
+		node = n
+		return false
+	})
+	return node
+}
+
+// GetN returns up to n distinct nodes that key is assigned to, in order of
+// preference, which is useful to select replicas of the key. Fewer than n
+// nodes are returned if the ring contains less than n nodes.
+//
+// Complexity: O(log n) + O(k) with k being the number of positions visited
+func (r *Ring[T]) GetN(key []byte, n int) []T {
+	if n > len(r.weights) {
+		n = len(r.weights)
+	}
+	if n <= 0 {
+		return nil
+	}
+	nodes := make([]T, 0, n)
+	seen := make(map[T]struct{}, n)
+	r.walk(key, func(node T) bool {
+		if _, ok := seen[node]; !ok {
+			seen[node] = struct{}{}
+			nodes = append(nodes, node)
+		}
+		return len(nodes) < n
+	})
+	return nodes
+}
+
+// walk calls f for each node position starting at the hash of key and going
+// around the ring once.
+func (r *Ring[T]) walk(key []byte, f func(T) bool) {
+	if r.positions.Len() == 0 {
+		return
+	}
+	h := maphash.Hash{}
+	h.SetSeed(r.hashseed)
+	h.Write(key)
+	start := h.Sum64()
+
+	more := true
+	r.positions.Range(start, func(_ uint64, node T) bool {
+		more = f(node)
+		return more
+	})
+	if more {
+		r.positions.Range(0, func(pos uint64, node T) bool {
+			return pos < start && f(node)
+		})
+	}
+}
+
+func (r *Ring[T]) rangePositions(node T, weight int, f func(uint64)) {
+	b := [8]byte{}
+	k := r.key(node)
+	h := maphash.Hash{}
+	h.SetSeed(r.hashseed)
+
+	for i := 0; i < weight; i++ {
+		binary.LittleEndian.PutUint64(b[:], uint64(i))
+		h.Reset()
+		h.Write(k)
+		h.Write(b[:])
+		f(h.Sum64())
+	}
+}
@@ -0,0 +1,185 @@
+package hashring
+
+import (
+	"strconv"
+	"testing"
+)
+
+func stringKey(s string) []byte { return []byte(s) }
+
+func intKey(i int) []byte { return strconv.AppendInt(nil, int64(i), 10) }
+
+func TestRingEmpty(t *testing.T) {
+	// The zero-value is a valid empty ring.
+	r := new(Ring[string])
+
+	if node := r.Get([]byte("key")); node != "" {
+		t.Errorf("wrong node returned by empty ring: got=%q want=%q", node, "")
+	}
+	if nodes := r.GetN([]byte("key"), 3); len(nodes) != 0 {
+		t.Errorf("wrong nodes returned by empty ring: got=%q want=[]", nodes)
+	}
+	if r.Remove("A") {
+		t.Error("removing a node from an empty ring reported success")
+	}
+}
+
+func TestRingDistribution(t *testing.T) {
+	r := NewRing(stringKey)
+	r.Add("A", 100)
+	r.Add("B", 100)
+	r.Add("C", 200)
+
+	if n := r.Len(); n != 3 {
+		t.Errorf("wrong number of nodes: got=%d want=3", n)
+	}
+
+	const numKeys = 10000
+	counts := map[string]int{}
+	for i := 0; i < numKeys; i++ {
+		counts[r.Get([]byte(strconv.Itoa(i)))]++
+	}
+
+	tests := []struct {
+		node string
+		want int
+	}{
+		{node: "A", want: numKeys / 4},
+		{node: "B", want: numKeys / 4},
+		{node: "C", want: numKeys / 2},
+	}
+
+	for _, test := range tests {
+		if got := counts[test.node]; got < test.want*2/3 || got > test.want*4/3 {
+			t.Errorf("unbalanced distribution of keys to node %s: got=%d want~%d", test.node, got, test.want)
+		}
+	}
+}
+
+func TestRingConsistency(t *testing.T) {
+	r := NewRing(stringKey)
+	for _, node := range []string{"A", "B", "C", "D"} {
+		r.Add(node, 100)
+	}
+
+	const numKeys = 1000
+	before := make([]string, numKeys)
+	for i := range before {
+		before[i] = r.Get([]byte(strconv.Itoa(i)))
+	}
+
+	if !r.Remove("B") {
+		t.Fatal("removing a node from the ring reported failure")
+	}
+
+	for i, node := range before {
+		after := r.Get([]byte(strconv.Itoa(i)))
+		if after == "B" {
+			t.Fatalf("key=%d assigned to removed node", i)
+		}
+		if node != "B" && node != after {
+			t.Errorf("key=%d moved from node %s to %s", i, node, after)
+		}
+	}
+
+	r.Add("B", 100)
+
+	for i, node := range before {
+		if after := r.Get([]byte(strconv.Itoa(i))); node != after {
+			t.Errorf("key=%d not restored to node %s after adding it back: got=%s", i, node, after)
+		}
+	}
+}
+
+func TestRingGetN(t *testing.T) {
+	r := NewRing(intKey)
+	for node := 1; node <= 5; node++ {
+		r.Add(node, 50)
+	}
+
+	for i := 0; i < 100; i++ {
+		key := []byte(strconv.Itoa(i))
+		nodes := r.GetN(key, 3)
+
+		if len(nodes) != 3 {
+			t.Fatalf("wrong number of nodes: got=%d want=3", len(nodes))
+		}
+		if nodes[0] != r.Get(key) {
+			t.Errorf("wrong first node: got=%d want=%d", nodes[0], r.Get(key))
+		}
+		if nodes[0] == nodes[1] || nodes[0] == nodes[2] || nodes[1] == nodes[2] {
+			t.Errorf("duplicate nodes returned: %v", nodes)
+		}
+	}
+
+	if nodes := r.GetN([]byte("key"), 10); len(nodes) != 5 {
+		t.Errorf("wrong number of nodes when asking for more than available: got=%d want=5", len(nodes))
+	}
+}
+
+func TestRingAddReplacesWeight(t *testing.T) {
+	r := NewRing(stringKey)
+	r.Add("A", 10)
+	r.Add("A", 20)
+
+	if w := r.Weight("A"); w != 20 {
+		t.Errorf("wrong weight: got=%d want=20", w)
+	}
+	if n := r.positions.Len(); n != 20 {
+		t.Errorf("wrong number of positions: got=%d want=20", n)
+	}
+
+	r.Add("A", 0)
+
+	if n := r.Len(); n != 0 {
+		t.Errorf("wrong number of nodes after adding with zero weight: got=%d want=0", n)
+	}
+	if n := r.positions.Len(); n != 0 {
+		t.Errorf("wrong number of positions: got=%d want=0", n)
+	}
+}
+
+func TestRingStructNodes(t *testing.T) {
+	type backend struct {
+		host string
+		port int
+	}
+	r := NewRing(func(b backend) []byte {
+		return strconv.AppendInt([]byte(b.host+":"), int64(b.port), 10)
+	})
+
+	backends := []backend{{"a", 80}, {"a", 81}, {"b", 80}}
+	for _, b := range backends {
+		r.Add(b, 100)
+	}
+	if n := r.positions.Len(); n != 300 {
+		t.Errorf("wrong number of positions: got=%d want=300", n)
+	}
+
+	counts := map[backend]int{}
+	for i := 0; i < 3000; i++ {
+		counts[r.Get([]byte(strconv.Itoa(i)))]++
+	}
+	for _, b := range backends {
+		if n := counts[b]; n < 500 || n > 1500 {
+			t.Errorf("unbalanced distribution of keys to backend %v: got=%d want~1000", b, n)
+		}
+	}
+
+	if !r.Remove(backend{"a", 81}) {
+		t.Fatal("removing a backend from the ring reported failure")
+	}
+	if n := r.positions.Len(); n != 200 {
+		t.Errorf("wrong number of positions after removing a backend: got=%d want=200", n)
+	}
+}
+
+func TestRingNotInitialized(t *testing.T) {
+	defer func() {
+		want := "hashring: Add called on a ring which was not initialized"
+		if r := recover(); r != want {
+			t.Errorf("wrong panic: got=%v want=%q", r, want)
+		}
+	}()
+	new(Ring[string]).Add("A", 1)
+}
@@ -6,10 +6,14 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"hash/fnv"
 	"hash/maphash"
 	"io"
 	"math/bits"
+	"os"
 	"sync"
+	"time"
+	"unsafe"
 
 	"github.com/segmentio/datastructures/v2/cache"
 )
@@ -39,6 +43,15 @@ const (
 	// For details on how this value was decided see this pull request:
 	// https://github.com/segmentio/datastructures/pull/4
 	numBuckets = 512
+
+	// The version of the memory layout of pages in the cache, which must be
+	// incremented when changes are made to how pages are laid out, so that the
+	// values returned by Fingerprint change as well.
+	layoutVersion = 1
+
+	// The number of reads returning no data and no error after which filling
+	// a page is abandoned with io.ErrNoProgress.
+	maxConsecutiveEmptyReads = 100
 )
 
 var (
@@ -49,10 +62,28 @@ var (
 
 // Config carries the configuration for the page cache.
 type Config struct {
-	PageSize  int64
-	PageCount int64
+	PageSize       int64
+	PageCount      int64
+	ReadAhead      int64
+	EvictionPolicy Policy
+	HashSeed       maphash.Seed
+	EvictionLog    int
+	Prefault       bool
 }
 
+// Policy represents the eviction policies that can be used by the cache.
+type Policy int
+
+const (
+	// LRU evicts the least recently used pages first.
+	LRU Policy = iota
+
+	// SLRU is a segmented LRU policy which evicts pages that were read only
+	// once before those that were read multiple times. It protects the cache
+	// from being flushed by large sequential scans.
+	SLRU
+)
+
 // DefaultConfig constructs a new Config instance initialized with the default
 // configuration.
 func DefaultConfig() *Config {
@@ -69,6 +100,44 @@ func (c *Config) Apply(options ...Option) {
 	}
 }
 
+// layout returns the page size (as a power of two) and page count of caches
+// created from c, after adjusting the configured values.
+func (c *Config) layout() (shift uint, pageCount int64) {
+	pageSize := c.PageSize
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+
+	pageCount = c.PageCount
+	if pageCount <= 0 {
+		pageCount = 1
+	}
+	if (pageCount % numBuckets) != 0 {
+		pageCount = ((pageCount / numBuckets) + 1) * numBuckets
+	}
+
+	shift = uint(bits.Len64(uint64(pageSize - 1)))
+	return shift, pageCount
+}
+
+// Fingerprint returns a value identifying the memory layout of caches created
+// from c. See Cache.Fingerprint for details.
+func (c *Config) Fingerprint() uint64 {
+	shift, pageCount := c.layout()
+	return fingerprint(shift, pageCount)
+}
+
+func fingerprint(shift uint, pageCount int64) uint64 {
+	b := [32]byte{}
+	binary.LittleEndian.PutUint64(b[0:], layoutVersion)
+	binary.LittleEndian.PutUint64(b[8:], uint64(1)<<shift)
+	binary.LittleEndian.PutUint64(b[16:], uint64(pageCount))
+	binary.LittleEndian.PutUint64(b[24:], numBuckets)
+	h := fnv.New64a()
+	h.Write(b[:])
+	return h.Sum64()
+}
+
 // Option is an interface implemented by options allowing configuration of new
 // Cache instances.
 type Option interface {
@@ -97,11 +166,137 @@ func PageCount(count int64) Option {
 	return option(func(config *Config) { config.PageCount = count })
 }
 
+// ReadAhead is a configuration option setting the maximum number of pages that
+// files may prefetch after a read.
+//
+// The read-ahead window of each file adapts to the access pattern: it doubles
+// every time a read starts where the previous one ended, up to the maximum set
+// by this option, and is reset to zero when a read jumps to a different part of
+// the file. Sequential scans quickly ramp up to prefetching large windows while
+// random reads do not pay for pages that would never be used.
+//
+// Default: 0 (disabled)
+func ReadAhead(count int64) Option {
+	return option(func(config *Config) { config.ReadAhead = count })
+}
+
+// EvictionPolicy is a configuration option setting the policy used to select
+// the pages to evict when the cache is full.
+//
+// Default: LRU
+func EvictionPolicy(policy Policy) Option {
+	return option(func(config *Config) { config.EvictionPolicy = policy })
+}
+
+// HashSeed is a configuration option setting the seed used to hash the pages
+// of files to the buckets of a Cache instance.
+//
+// Caches created with the same seed place pages in the same buckets, which
+// makes the distribution of pages reproducible in tests and benchmarks. Note
+// that seeds can only be generated by maphash.MakeSeed, so the distribution is
+// only reproducible within a process.
+//
+// Default: a random seed generated for each cache
+func HashSeed(seed maphash.Seed) Option {
+	return option(func(config *Config) { config.HashSeed = seed })
+}
+
+// EvictionLog is a configuration option setting the number of recent page
+// evictions recorded by the cache, which are returned by RecentEvictions.
+//
+// The log helps diagnose files thrashing each other's pages in the cache. All
+// evictions are recorded in a single log guarded by a mutex, so it is intended
+// for debugging rather than to be enabled permanently on busy caches.
+//
+// Default: 0 (disabled)
+func EvictionLog(size int) Option {
+	return option(func(config *Config) { config.EvictionLog = size })
+}
+
+// Prefault is a configuration option instructing the cache to touch all of its
+// page memory when it is created.
+//
+// The memory is allocated when the cache is created, but is usually only
+// faulted in by the operating system when first written to, which adds latency
+// to the reads filling pages of the cache for the first time. Prefaulting pays
+// this cost up front, so the cache has predictable latency from the start, at
+// the expense of a slower construction.
+//
+// Default: false
+func Prefault(enable bool) Option {
+	return option(func(config *Config) { config.Prefault = enable })
+}
+
+// FileConfig carries the configuration of files created by Cache.NewFile.
+type FileConfig struct {
+	SmallReadSize int64
+	MaxAge        time.Duration
+	Fallback      io.ReaderAt
+}
+
+// FileOption is an interface implemented by options allowing configuration of
+// files created by Cache.NewFile.
+type FileOption interface {
+	ConfigureFile(*FileConfig)
+}
+
+type fileOption func(*FileConfig)
+
+func (opt fileOption) ConfigureFile(config *FileConfig) { opt(config) }
+
+// SmallReadSize is a file configuration option setting the size under which
+// reads that miss the cache are served directly from the underlying file,
+// without loading the pages in the cache.
+//
+// Loading a full page on each miss benefits from locality but is wasteful for
+// random point reads of records much smaller than the page size; this option
+// allows tuning this tradeoff for each file. Pages already present in the
+// cache are still used to serve small reads.
+//
+// Default: 0 (disabled)
+func SmallReadSize(size int64) FileOption {
+	return fileOption(func(config *FileConfig) { config.SmallReadSize = size })
+}
+
+// MaxAge is a file configuration option setting the maximum amount of time
+// that pages of the file are trusted after being loaded in the cache. Reads of
+// pages older than the maximum age are served by re-loading the pages from the
+// underlying file, giving eventually consistent reads of files that change
+// slowly without requiring explicit invalidation.
+//
+// Default: 0 (pages never expire)
+func MaxAge(d time.Duration) FileOption {
+	return fileOption(func(config *FileConfig) { config.MaxAge = d })
+}
+
+// Fallback is a file configuration option setting a reader consulted instead
+// of the underlying file when reads miss the cache. The fallback must present
+// the same content as the file; it is typically a file created by a larger but
+// slower Cache wrapping the same underlying file, which composes the caches in
+// tiers: pages missing from the local cache are loaded from the fallback tier,
+// which itself reads from the underlying file on a miss.
+//
+// The fallback does not need to serve all reads: when it returns an error (e.g.
+// ErrNoPages if the fallback tier has no pages available) or fewer bytes than
+// requested, the remaining bytes are read from the underlying file.
+//
+// Default: nil (reads are served by the underlying file)
+func Fallback(r io.ReaderAt) FileOption {
+	return fileOption(func(config *FileConfig) { config.Fallback = r })
+}
+
 // Cache instances implement the page caching layer of files.
+//
+// Cache instances are safe to use concurrently from multiple goroutines,
+// including when reads of the same pages are racing: pages are only filled by
+// the goroutine which allocated them, and only become visible to other readers
+// once they have been fully written.
 type Cache struct {
-	hashseed maphash.Seed
-	shift    uint
-	pages    []byte
+	hashseed  maphash.Seed
+	shift     uint
+	readAhead int64
+	pages     []byte
+	evictions *evictionLog
 	// The cache is divided into buckets, each bucket holding a section of the
 	// total page count. Each bucket can synchronize cache access and evict
 	// outdated pages independently. Having multiple buckets helps scale cache
@@ -121,29 +316,26 @@ func New(options ...Option) *Cache {
 // NewWithConfig is like New but uses a Config instance to pass the cache
 // configuration instead of a list of options.
 func NewWithConfig(config *Config) *Cache {
-	pageSize := config.PageSize
-	if pageSize <= 0 {
-		pageSize = DefaultPageSize
-	}
+	shift, pageCount := config.layout()
+	pageSize := int64(1) << shift
 
-	pageCount := config.PageCount
-	if pageCount <= 0 {
-		pageCount = 1
-	}
-	if (pageCount % numBuckets) != 0 {
-		pageCount = ((pageCount / numBuckets) + 1) * numBuckets
+	hashseed := config.HashSeed
+	if hashseed == (maphash.Seed{}) {
+		hashseed = maphash.MakeSeed()
 	}
 
-	shift := uint(bits.Len64(uint64(pageSize - 1)))
-	pageSize = int64(1) << shift
-
 	c := &Cache{
-		hashseed: maphash.MakeSeed(),
-		shift:    shift,
+		hashseed:  hashseed,
+		shift:     shift,
+		readAhead: config.ReadAhead,
 		// TODO: should we make the allocator configurable?
 		pages: make([]byte, pageSize*pageCount),
 	}
 
+	if config.EvictionLog > 0 {
+		c.evictions = &evictionLog{entries: make([]Eviction, 0, config.EvictionLog)}
+	}
+
 	pages := make([]page, pageCount)
 	for i := range pages {
 		pages[i].offset = uint32(i)
@@ -154,8 +346,12 @@ func NewWithConfig(config *Config) *Cache {
 		off := (i + 0) * bucketSize
 		end := (i + 1) * bucketSize
 		c.buckets[i].pages = pages[off:end:end]
+		c.buckets[i].cache = newBucketCache(config.EvictionPolicy)
 	}
 
+	if config.Prefault {
+		c.prefault()
+	}
 	return c
 }
 
@@ -163,19 +359,29 @@ func NewWithConfig(config *Config) *Cache {
 // unique identifier intended to uniquely represent the file within the cache.
 // If multiple io.ReaderAt interfaces point at the same underlying file, they
 // could share the same id to reference the same pages in the cache.
-func (c *Cache) NewFile(id uint32, file io.ReaderAt, size int64) io.ReaderAt {
+//
+// The returned value also has a ReadAheadWindow method returning the number of
+// pages that the file currently prefetches after each read.
+func (c *Cache) NewFile(id uint32, file io.ReaderAt, size int64, options ...FileOption) io.ReaderAt {
+	config := new(FileConfig)
+	for _, opt := range options {
+		opt.ConfigureFile(config)
+	}
 	return &cachedFile{
-		cache: c,
-		id:    id,
-		file:  file,
-		size:  size,
+		cache:     c,
+		id:        id,
+		file:      file,
+		size:      size,
+		smallRead: config.SmallReadSize,
+		maxAge:    config.MaxAge,
+		fallback:  config.Fallback,
 	}
 }
 
 func (c *Cache) bucketOf(key region) *bucket {
-	b := [8]byte{}
+	b := [12]byte{}
 	binary.LittleEndian.PutUint32(b[:4], key.object)
-	binary.LittleEndian.PutUint32(b[4:], key.offset)
+	binary.LittleEndian.PutUint64(b[4:], key.offset)
 	// This hashing strategy ensures that we will not see hotspots from cache
 	// access, pages are spread evenly across buckets, independently of their
 	// position or files that they belong to. Those properties must be retained
@@ -192,6 +398,17 @@ func (c *Cache) bytes(page page) []byte {
 	return c.pages[offset : offset+length]
 }
 
+// Fingerprint returns a value identifying the memory layout of the cache,
+// derived from the page size, page count, and number of buckets.
+//
+// Programs persisting the page memory of a cache can store the fingerprint
+// alongside it, and compare it to the fingerprint of their configuration when
+// reloading, in order to detect incompatible layouts without reading the
+// content of pages.
+func (c *Cache) Fingerprint() uint64 {
+	return fingerprint(c.shift, int64(len(c.pages))>>c.shift)
+}
+
 // Stats is a structure carrying statistics collected on cache access.
 //
 // All counters are absolute values accumulated since a cache instance was
@@ -229,11 +446,204 @@ func (c *Cache) Stats() (stats Stats) {
 	return stats
 }
 
+// MemoryUsage returns the memory footprint of the cache, in bytes.
+//
+// The page memory is allocated when the cache is created and accounts for the
+// majority of the footprint; the remainder is an estimate of the memory used
+// by the data structures tracking which pages are in use.
+func (c *Cache) MemoryUsage() int64 {
+	size := int64(unsafe.Sizeof(*c)) + int64(len(c.pages))
+	for i := range c.buckets {
+		size += c.buckets[i].memoryUsage()
+	}
+	return size
+}
+
+// prefault writes to every page of the memory backing the cache, forcing the
+// operating system to map it to physical memory. It must only be called when
+// the cache is created, since it overwrites the content of the pages.
+func (c *Cache) prefault() {
+	pageSize := os.Getpagesize()
+	for i := 0; i < len(c.pages); i += pageSize {
+		c.pages[i] = 0
+	}
+}
+
+// Compact releases memory held by the free lists of the cache buckets when
+// they are mostly empty.
+//
+// Free lists are allocated to hold every page of the cache when it is created,
+// and retain this capacity after the pages are allocated to cache file data.
+// Programs which keep a cache full for long periods of time may call Compact
+// to reclaim the unused capacity; the free lists grow again as needed when
+// pages are released.
+func (c *Cache) Compact() {
+	for i := range c.buckets {
+		c.buckets[i].compact()
+	}
+}
+
+// Eviction represents the eviction of a page from the cache, identified by the
+// id of the file it belonged to and the byte offset of the page in the file.
+type Eviction struct {
+	ID     uint32
+	Offset int64
+}
+
+// RecentEvictions returns the most recent page evictions of the cache, in the
+// order they happened. The method returns nil unless the cache was configured
+// with the EvictionLog option, in which case up to the configured number of
+// evictions are returned.
+func (c *Cache) RecentEvictions() []Eviction {
+	if c.evictions == nil {
+		return nil
+	}
+	return c.evictions.snapshot()
+}
+
+// evictionLog is a ring buffer of the most recent evictions.
+type evictionLog struct {
+	mutex   sync.Mutex
+	entries []Eviction
+	next    int
+}
+
+func (l *evictionLog) record(e Eviction) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if len(l.entries) < cap(l.entries) {
+		l.entries = append(l.entries, e)
+	} else {
+		l.entries[l.next] = e
+		l.next = (l.next + 1) % len(l.entries)
+	}
+}
+
+func (l *evictionLog) snapshot() []Eviction {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	evictions := make([]Eviction, 0, len(l.entries))
+	evictions = append(evictions, l.entries[l.next:]...)
+	evictions = append(evictions, l.entries[:l.next]...)
+	return evictions
+}
+
+// ForEachPage calls f for each page of the cache. Pages holding file data are
+// reported with the id of the file and the byte offset of the page within the
+// file, and resident set to true. Free pages are reported with resident set to
+// false, a zero id, and an offset of -1. Pages being loaded by concurrent reads
+// are not reported. If f returns false, the iteration is stopped.
+//
+// The method is intended for inspection and debugging tools. Buckets of the
+// cache are snapshotted one at a time, so locks are not held while f is called
+// but the pages reported may not represent the state of the cache at a single
+// point in time.
+func (c *Cache) ForEachPage(f func(id uint32, offset int64, resident bool) bool) {
+	var keys []region
+	for i := range c.buckets {
+		var free int
+		keys, free = c.buckets[i].snapshot(keys[:0])
+
+		for _, key := range keys {
+			if !f(key.object, int64(key.offset)<<c.shift, true) {
+				return
+			}
+		}
+		for j := 0; j < free; j++ {
+			if !f(0, -1, false) {
+				return
+			}
+		}
+	}
+}
+
 type cachedFile struct {
-	cache *Cache
-	id    uint32
-	file  io.ReaderAt
-	size  int64
+	cache     *Cache
+	id        uint32
+	file      io.ReaderAt
+	size      int64
+	smallRead int64
+	maxAge    time.Duration
+	fallback  io.ReaderAt
+
+	mutex  sync.Mutex
+	next   int64 // index of the page following the last read
+	window int64 // number of pages to read ahead
+}
+
+// readFull reads len(b) bytes at offset off of the file, which must be within
+// the file size. The fallback is consulted first if the file has one; the bytes
+// it did not provide, because of an error or a short read, are read from the
+// underlying file.
+func (f *cachedFile) readFull(b []byte, off int64) error {
+	if f.fallback != nil {
+		n, _ := f.fallback.ReadAt(b, off)
+		b, off = b[n:], off+int64(n)
+	}
+
+	// The underlying file may return short reads (e.g. when it decompresses
+	// data or reads from the network), keep reading until the buffer is
+	// filled.
+	for rn, emptyReads := 0, 0; rn < len(b); {
+		n, err := f.file.ReadAt(b[rn:], off+int64(rn))
+		if rn += n; rn == len(b) {
+			break
+		}
+
+		if err == nil && n == 0 {
+			if emptyReads++; emptyReads == maxConsecutiveEmptyReads {
+				err = io.ErrNoProgress
+			}
+		} else {
+			emptyReads = 0
+		}
+
+		if errors.Is(err, io.EOF) {
+			// The file is shorter than its declared size, the content
+			// would be incomplete.
+			err = io.ErrUnexpectedEOF
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReadAheadWindow returns the current size of the read-ahead window of f, in
+// number of pages.
+func (f *cachedFile) ReadAheadWindow() int64 {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.window
+}
+
+// readAhead records a read of the pages in the range [first, last] and returns
+// the number of pages to prefetch after the last one.
+func (f *cachedFile) readAhead(first, last int64) int64 {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if first == f.next || first == f.next-1 {
+		switch {
+		case f.window == 0:
+			f.window = 1
+		case f.window < f.cache.readAhead:
+			f.window *= 2
+		}
+		if f.window > f.cache.readAhead {
+			f.window = f.cache.readAhead
+		}
+	} else {
+		f.window = 0
+	}
+
+	f.next = last + 1
+	return f.window
 }
 
 func (f *cachedFile) ReadAt(b []byte, off int64) (n int, err error) {
@@ -254,31 +664,55 @@ func (f *cachedFile) ReadAt(b []byte, off int64) (n int, err error) {
 	shift := cache.shift
 	pageSize := int64(1) << shift
 
+	if cache.readAhead > 0 {
+		first := off >> shift
+		last := (off + int64(len(b)) - 1) >> shift
+		defer func() {
+			if window := f.readAhead(first, last); window > 0 && err == nil {
+				f.prefetch(last+1, window)
+			}
+		}()
+	}
+
+	bypass := int64(len(b)) < f.smallRead
+
+	// Pages loaded before this time are expired and must be reloaded.
+	expire := int64(0)
+	if f.maxAge > 0 {
+		expire = time.Now().Add(-f.maxAge).UnixNano()
+	}
+
 	for {
 		key := region{
 			object: f.id,
-			offset: uint32(off >> shift),
+			offset: uint64(off >> shift),
 		}
 
 		pageOffset := int64(key.offset) << shift
 		readOffset := off - pageOffset
 
-		if bucket := cache.bucketOf(key); !bucket.read(b[n:], key, readOffset, cache) {
-			page, ok := bucket.get()
-			if !ok {
-				return n, ErrNoPages
+		bucket := cache.bucketOf(key)
+		switch {
+		case bucket.read(b[n:], key, readOffset, expire, cache):
+		case bypass:
+			chunk := b[n:]
+			if limit := pageSize - readOffset; limit < int64(len(chunk)) {
+				chunk = chunk[:limit]
 			}
-			data := cache.bytes(page)
-
-			rn, err := f.file.ReadAt(data, pageOffset)
-			if rn < len(data) && !errors.Is(err, io.EOF) {
-				if err == nil {
-					err = io.ErrNoProgress
-				}
+			if err := f.readFull(chunk, off); err != nil {
 				return n, err
 			}
-
-			copy(b[n:], data[readOffset:rn])
+		default:
+			// The page returned by get is owned exclusively by this goroutine
+			// until it is handed back to the bucket by put, which is what
+			// allows filling it without holding the bucket mutex. Concurrent
+			// misses on the same region each fill a distinct page, the last
+			// one to be put wins and the other is returned to the free list.
+			page, data, err := f.fill(bucket, key)
+			if err != nil {
+				return n, err
+			}
+			copy(b[n:], data[readOffset:])
 			bucket.put(key, page)
 		}
 
@@ -292,18 +726,66 @@ func (f *cachedFile) ReadAt(b []byte, off int64) (n int, err error) {
 	}
 }
 
+// fill allocates a page from the bucket and reads the file content of the
+// region into it. On success, the caller must publish the page with put.
+func (f *cachedFile) fill(bucket *bucket, key region) (page, []byte, error) {
+	page, ok := bucket.get(f.cache)
+	if !ok {
+		return page, nil, ErrNoPages
+	}
+	data := f.cache.bytes(page)
+	offset := int64(key.offset) << f.cache.shift
+	if limit := f.size - offset; limit < int64(len(data)) {
+		data = data[:limit]
+	}
+
+	if err := f.readFull(data, offset); err != nil {
+		bucket.free(page)
+		return page, nil, err
+	}
+
+	return page, data, nil
+}
+
+// prefetch loads count pages starting at the given page index in the cache.
+// Errors are not reported since no caller is waiting for the data; prefetching
+// simply stops when a page cannot be loaded.
+func (f *cachedFile) prefetch(index, count int64) {
+	shift := f.cache.shift
+
+	for i := index; i < index+count && (i<<shift) < f.size; i++ {
+		key := region{
+			object: f.id,
+			offset: uint64(i),
+		}
+		bucket := f.cache.bucketOf(key)
+		if bucket.contains(key) {
+			continue
+		}
+		page, _, err := f.fill(bucket, key)
+		if err != nil {
+			return
+		}
+		bucket.put(key, page)
+	}
+}
+
+// region is the key of pages in the cache. The offset is the index of the page
+// in the file, it is 64 bits wide so files of any size can be addressed
+// independently of the page size.
 type region struct {
 	object uint32
-	offset uint32
+	offset uint64
 }
 
 type page struct {
 	offset uint32
+	loaded int64 // time at which the page was put in the cache
 }
 
 type bucket struct {
 	mutex sync.Mutex
-	cache cache.LRU[region, page]
+	cache bucketCache
 	pages []page
 	bucketStats
 }
@@ -317,11 +799,32 @@ type bucketStats struct {
 	frees     int64
 }
 
-func (b *bucket) read(data []byte, key region, off int64, cache *Cache) bool {
+// bucketCache is the interface of the cache backends of buckets, which must
+// support looking up pages without promoting them.
+type bucketCache interface {
+	cache.Interface[region, page]
+	Peek(region) (page, bool)
+}
+
+func newBucketCache(policy Policy) bucketCache {
+	switch policy {
+	case SLRU:
+		return new(cache.SLRU[region, page])
+	default:
+		return new(cache.LRU[region, page])
+	}
+}
+
+func (b *bucket) read(data []byte, key region, off, expire int64, cache *Cache) bool {
 	b.mutex.Lock()
 	defer b.mutex.Unlock()
 
 	page, ok := b.cache.Lookup(key)
+	if ok && page.loaded < expire {
+		// The page will be replaced when the caller puts a fresh copy in the
+		// cache, at which point the expired page is returned to the free list.
+		ok = false
+	}
 	if ok {
 		b.hits++
 		copy(data, cache.bytes(page)[off:])
@@ -330,7 +833,22 @@ func (b *bucket) read(data []byte, key region, off int64, cache *Cache) bool {
 	return ok
 }
 
-func (b *bucket) get() (page, bool) {
+// contains returns true if the bucket holds a page for key. The page is not
+// promoted, checking which pages need to be read ahead must not protect the
+// pages of the file from eviction.
+func (b *bucket) contains(key region) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	_, ok := b.cache.Peek(key)
+	return ok
+}
+
+// get returns a page that can be filled by the caller. The page is removed
+// from both the free list and the LRU cache while the bucket mutex is held, so
+// no other goroutine can obtain or read it until the caller passes it to put.
+// Evictions are recorded in the eviction log of the cache, if enabled.
+func (b *bucket) get(cache *Cache) (page, bool) {
 	b.mutex.Lock()
 	defer b.mutex.Unlock()
 
@@ -341,16 +859,25 @@ func (b *bucket) get() (page, bool) {
 		return page, true
 	}
 
-	_, page, evicted := b.cache.Evict()
+	key, page, evicted := b.cache.Evict()
 	if evicted {
 		b.evictions++
+		if cache.evictions != nil {
+			cache.evictions.record(Eviction{ID: key.object, Offset: int64(key.offset) << cache.shift})
+		}
 		return page, true
 	}
 
 	return page, false
 }
 
+// put publishes a page filled by the caller, making it visible to readers of
+// the bucket. If another goroutine had already published a page for the same
+// key, the previous page is returned to the free list; this is safe because
+// readers only access page bytes while holding the bucket mutex.
 func (b *bucket) put(key region, page page) {
+	page.loaded = time.Now().UnixNano()
+
 	b.mutex.Lock()
 	defer b.mutex.Unlock()
 
@@ -363,6 +890,54 @@ func (b *bucket) put(key region, page page) {
 	b.inserts++
 }
 
+// free returns a page obtained from get to the free list, when the caller was
+// unable to fill it.
+func (b *bucket) free(page page) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.pages = append(b.pages, page)
+	b.frees++
+}
+
+// compact reallocates the free list if less than half of its capacity is in
+// use. Since the initial free lists of all buckets share a single array, the
+// memory is only reclaimed once every bucket has released it.
+func (b *bucket) compact() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if len(b.pages) < cap(b.pages)/2 {
+		var pages []page
+		if len(b.pages) != 0 {
+			pages = make([]page, len(b.pages))
+			copy(pages, b.pages)
+		}
+		b.pages = pages
+	}
+}
+
+func (b *bucket) memoryUsage() int64 {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	size := int64(cap(b.pages)) * int64(unsafe.Sizeof(page{}))
+	if m, ok := b.cache.(interface{ MemoryUsage() int64 }); ok {
+		size += m.MemoryUsage()
+	}
+	return size
+}
+
+func (b *bucket) snapshot(keys []region) ([]region, int) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.cache.Range(func(key region, _ page) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys, len(b.pages)
+}
+
 func (b *bucket) stats() (stats bucketStats) {
 	b.mutex.Lock()
 	defer b.mutex.Unlock()
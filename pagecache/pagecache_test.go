@@ -2,8 +2,11 @@ package pagecache_test
 
 import (
 	"bytes"
+	"fmt"
+	"hash/maphash"
 	"io"
 	"math/rand"
+	"strings"
 	"sync"
 	"testing"
 	"testing/iotest"
@@ -28,8 +31,32 @@ func TestPageCache(t *testing.T) {
 		pagecache.PageCount(1024),
 	)
 
+	testPageCacheReaders(t, cache, b.Bytes())
+}
+
+func TestPageCacheSLRU(t *testing.T) {
+	const size = 2e6 // ~2MB
+	r := rand.New(rand.NewSource(3))
+	b := new(bytes.Buffer)
+	b.Grow(size)
+
+	_, err := io.CopyN(b, r, size)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cache := pagecache.New(
+		pagecache.PageSize(512),
+		pagecache.PageCount(1024),
+		pagecache.EvictionPolicy(pagecache.SLRU),
+	)
+
+	testPageCacheReaders(t, cache, b.Bytes())
+}
+
+func testPageCacheReaders(t *testing.T, cache *pagecache.Cache, data []byte) {
+	size := int64(len(data))
 	wg := sync.WaitGroup{}
-	data := b.Bytes()
 
 	for i := 0; i < 2; i++ {
 		wg.Add(1)
@@ -46,6 +73,590 @@ func TestPageCache(t *testing.T) {
 	wg.Wait()
 }
 
+func TestPageCacheConcurrentFill(t *testing.T) {
+	const size = 64 * 1024
+	r := rand.New(rand.NewSource(5))
+	data := make([]byte, size)
+	r.Read(data)
+
+	// A cache with a single page per bucket forces goroutines to compete for
+	// the same pages, exercising the window between allocating and publishing
+	// pages (run with -race to detect concurrent access to page bytes).
+	cache := pagecache.New(
+		pagecache.PageSize(256),
+		pagecache.PageCount(1),
+	)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(seed))
+			f := cache.NewFile(1, bytes.NewReader(data), size)
+			b := make([]byte, 100)
+
+			for j := 0; j < 1000; j++ {
+				off := r.Int63n(size - int64(len(b)))
+				n, err := f.ReadAt(b, off)
+				if err != nil && err != pagecache.ErrNoPages {
+					t.Error(err)
+					return
+				}
+				if !bytes.Equal(b[:n], data[off:off+int64(n)]) {
+					t.Errorf("wrong data read at offset %d", off)
+					return
+				}
+			}
+		}(int64(i))
+	}
+
+	wg.Wait()
+}
+
+type shortReaderAt struct {
+	io.ReaderAt
+	max int
+}
+
+func (r *shortReaderAt) ReadAt(b []byte, off int64) (int, error) {
+	if len(b) > r.max {
+		b = b[:r.max]
+	}
+	n, err := r.ReaderAt.ReadAt(b, off)
+	if err == io.EOF && n == len(b) {
+		err = nil
+	}
+	return n, err
+}
+
+func TestPageCacheShortReads(t *testing.T) {
+	const size = 10000
+	data := make([]byte, size)
+	rand.New(rand.NewSource(11)).Read(data)
+
+	cache := pagecache.New(
+		pagecache.PageSize(1024),
+		pagecache.PageCount(1024),
+	)
+
+	file := cache.NewFile(1, &shortReaderAt{ReaderAt: bytes.NewReader(data), max: 100}, size)
+
+	if err := iotest.TestReader(io.NewSectionReader(file, 0, size), data); err != nil {
+		t.Error(err)
+	}
+
+	// Declaring a size larger than the file must not cache incomplete pages.
+	truncated := cache.NewFile(2, bytes.NewReader(data[:size-10]), size)
+	b := make([]byte, 100)
+
+	if _, err := truncated.ReadAt(b, size-100); err != io.ErrUnexpectedEOF {
+		t.Errorf("wrong error reading truncated file: got=%v want=%v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestPageCacheSmallReadSize(t *testing.T) {
+	const size = 64 * 1024
+	data := make([]byte, size)
+	rand.New(rand.NewSource(13)).Read(data)
+
+	cache := pagecache.New(
+		pagecache.PageSize(4096),
+		pagecache.PageCount(1024),
+	)
+
+	file := cache.NewFile(1, bytes.NewReader(data), size, pagecache.SmallReadSize(256))
+	b := make([]byte, 100)
+
+	for _, off := range []int64{0, 4090, 10000, size - 50} {
+		n, err := file.ReadAt(b, off)
+		if err != nil && err != io.EOF {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b[:n], data[off:off+int64(n)]) {
+			t.Errorf("wrong data read at offset %d", off)
+		}
+	}
+
+	if stats := cache.Stats(); stats.Inserts != 0 {
+		t.Errorf("small reads inserted pages in the cache: %d", stats.Inserts)
+	}
+
+	// Large reads still load pages in the cache, which are then used to serve
+	// small reads.
+	if _, err := file.ReadAt(make([]byte, 1000), 0); err != nil {
+		t.Fatal(err)
+	}
+	stats := cache.Stats()
+	if _, err := file.ReadAt(b, 0); err != nil {
+		t.Fatal(err)
+	}
+	if hits := cache.Stats().Hits - stats.Hits; hits != 1 {
+		t.Errorf("small read of a cached page was not a cache hit: hits=%d", hits)
+	}
+}
+
+// countingReaderAt counts the calls to ReadAt of the underlying reader.
+type countingReaderAt struct {
+	reader io.ReaderAt
+	reads  int
+}
+
+func (r *countingReaderAt) ReadAt(b []byte, off int64) (int, error) {
+	r.reads++
+	return r.reader.ReadAt(b, off)
+}
+
+func TestPageCacheFallback(t *testing.T) {
+	const size = 64 * 1024
+	data := make([]byte, size)
+	rand.New(rand.NewSource(17)).Read(data)
+	origin := &countingReaderAt{reader: bytes.NewReader(data)}
+
+	tier2 := pagecache.New(
+		pagecache.PageSize(4096),
+		pagecache.PageCount(1024),
+	)
+	fallback := tier2.NewFile(1, origin, size)
+
+	for i := 0; i < 2; i++ {
+		tier1 := pagecache.New(
+			pagecache.PageSize(4096),
+			pagecache.PageCount(1024),
+		)
+		file := tier1.NewFile(1, origin, size, pagecache.Fallback(fallback))
+		b := make([]byte, 5000)
+
+		for _, off := range []int64{0, 10000, size - 5000} {
+			if _, err := file.ReadAt(b, off); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(b, data[off:off+int64(len(b))]) {
+				t.Errorf("wrong data read at offset %d", off)
+			}
+		}
+
+		if stats := tier1.Stats(); stats.Inserts != 6 {
+			t.Errorf("wrong number of pages inserted in the first tier: got=%d want=6", stats.Inserts)
+		}
+	}
+
+	// The second pass was served by the fallback tier.
+	if stats := tier2.Stats(); stats.Inserts != 6 || stats.Hits != 6 {
+		t.Errorf("wrong usage of the fallback tier: inserts=%d hits=%d want=6", stats.Inserts, stats.Hits)
+	}
+	if origin.reads != 6 {
+		t.Errorf("wrong number of reads from the underlying file: got=%d want=6", origin.reads)
+	}
+}
+
+func TestPageCacheFallbackFailure(t *testing.T) {
+	const size = 64 * 1024
+	data := make([]byte, size)
+	rand.New(rand.NewSource(19)).Read(data)
+
+	tests := []struct {
+		scenario string
+		fallback io.ReaderAt
+	}{
+		{
+			scenario: "fallback returns ErrNoPages",
+			fallback: failingReaderAt{err: pagecache.ErrNoPages},
+		},
+		{
+			scenario: "fallback returns short reads",
+			fallback: &shortReaderAt{ReaderAt: bytes.NewReader(data), max: 1000},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			origin := &countingReaderAt{reader: bytes.NewReader(data)}
+			cache := pagecache.New(
+				pagecache.PageSize(4096),
+				pagecache.PageCount(1024),
+			)
+			file := cache.NewFile(1, origin, size, pagecache.Fallback(test.fallback))
+			b := make([]byte, 5000)
+
+			for _, off := range []int64{0, 10000, size - 5000} {
+				if _, err := file.ReadAt(b, off); err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(b, data[off:off+int64(len(b))]) {
+					t.Errorf("wrong data read at offset %d", off)
+				}
+			}
+
+			if origin.reads != 6 {
+				t.Errorf("wrong number of reads from the underlying file: got=%d want=6", origin.reads)
+			}
+		})
+	}
+}
+
+// failingReaderAt is a reader which fails all reads with the same error.
+type failingReaderAt struct{ err error }
+
+func (r failingReaderAt) ReadAt([]byte, int64) (int, error) { return 0, r.err }
+
+// offsetReaderAt is a sparse file where each 8 bytes word contains its offset.
+type offsetReaderAt struct{}
+
+func (offsetReaderAt) ReadAt(b []byte, off int64) (int, error) {
+	for i := range b {
+		word := (off + int64(i)) &^ 7
+		b[i] = byte(word >> (8 * uint((off+int64(i))&7)))
+	}
+	return len(b), nil
+}
+
+func TestPageCacheLargeOffsets(t *testing.T) {
+	const size = 1 << 50
+
+	cache := pagecache.New(
+		pagecache.PageSize(512),
+		pagecache.PageCount(1024),
+	)
+
+	file := cache.NewFile(1, offsetReaderAt{}, size)
+	want := make([]byte, 64)
+	b := make([]byte, 64)
+
+	// With 512 bytes pages, 32 bits page indexes can only address 2 TiB, the
+	// second offset would alias the first one if the page index overflowed.
+	for _, off := range []int64{1 << 20, 1<<41 + 1<<20, size - 64} {
+		if _, err := file.ReadAt(b, off); err != nil {
+			t.Fatal(err)
+		}
+		offsetReaderAt{}.ReadAt(want, off)
+		if !bytes.Equal(b, want) {
+			t.Errorf("wrong data read at offset %d", off)
+		}
+	}
+}
+
+func TestPageCacheForEachPage(t *testing.T) {
+	cache := pagecache.New(
+		pagecache.PageSize(1024),
+		pagecache.PageCount(1024),
+	)
+
+	data := make([]byte, 4096)
+	if _, err := cache.NewFile(1, bytes.NewReader(data), 4096).ReadAt(data[:2048], 1024); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.NewFile(2, bytes.NewReader(data), 4096).ReadAt(data[:10], 0); err != nil {
+		t.Fatal(err)
+	}
+
+	resident := map[[2]int64]bool{}
+	free := 0
+	cache.ForEachPage(func(id uint32, offset int64, isResident bool) bool {
+		if isResident {
+			resident[[2]int64{int64(id), offset}] = true
+		} else {
+			free++
+		}
+		return true
+	})
+
+	want := [][2]int64{{1, 1024}, {1, 2048}, {2, 0}}
+	if len(resident) != len(want) {
+		t.Errorf("wrong number of resident pages: got=%d want=%d", len(resident), len(want))
+	}
+	for _, page := range want {
+		if !resident[page] {
+			t.Errorf("page not reported as resident: id=%d offset=%d", page[0], page[1])
+		}
+	}
+	if free != 1024-len(want) {
+		t.Errorf("wrong number of free pages: got=%d want=%d", free, 1024-len(want))
+	}
+
+	calls := 0
+	cache.ForEachPage(func(uint32, int64, bool) bool {
+		calls++
+		return false
+	})
+	if calls != 1 {
+		t.Errorf("iteration did not stop when the callback returned false: %d calls", calls)
+	}
+}
+
+func TestPageCacheFingerprint(t *testing.T) {
+	config := pagecache.DefaultConfig()
+	config.Apply(pagecache.PageSize(1000), pagecache.PageCount(1000))
+	cache := pagecache.NewWithConfig(config)
+
+	if config.Fingerprint() != cache.Fingerprint() {
+		t.Error("fingerprints of the cache and its configuration differ")
+	}
+
+	// The page size and count are adjusted to the same values.
+	same := &pagecache.Config{PageSize: 1024, PageCount: 1024}
+	if same.Fingerprint() != cache.Fingerprint() {
+		t.Error("fingerprints of equivalent configurations differ")
+	}
+
+	for _, other := range []*pagecache.Config{
+		{PageSize: 2048, PageCount: 1024},
+		{PageSize: 1024, PageCount: 2048},
+	} {
+		if other.Fingerprint() == cache.Fingerprint() {
+			t.Errorf("fingerprints of different layouts are equal: page size=%d count=%d", other.PageSize, other.PageCount)
+		}
+	}
+}
+
+func TestPageCacheMaxAge(t *testing.T) {
+	cache := pagecache.New(
+		pagecache.PageSize(1024),
+		pagecache.PageCount(1024),
+	)
+
+	data := make([]byte, 4096)
+	fresh := cache.NewFile(1, bytes.NewReader(data), 4096, pagecache.MaxAge(time.Hour))
+	stale := cache.NewFile(1, bytes.NewReader(data), 4096, pagecache.MaxAge(time.Nanosecond))
+	b := make([]byte, 10)
+
+	if _, err := fresh.ReadAt(b, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// Modify the underlying file, pages younger than the max age are trusted
+	// while older pages are reloaded.
+	data[0] = 42
+	time.Sleep(time.Millisecond)
+
+	if _, err := fresh.ReadAt(b, 0); err != nil {
+		t.Fatal(err)
+	} else if b[0] != 0 {
+		t.Errorf("page younger than max age was reloaded: %d", b[0])
+	}
+
+	if _, err := stale.ReadAt(b, 0); err != nil {
+		t.Fatal(err)
+	} else if b[0] != 42 {
+		t.Errorf("page older than max age was not reloaded: %d", b[0])
+	}
+
+	if _, err := fresh.ReadAt(b, 0); err != nil {
+		t.Fatal(err)
+	} else if b[0] != 42 {
+		t.Errorf("reloaded page was not updated in the cache: %d", b[0])
+	}
+
+	if stats := cache.Stats(); stats.Frees != 1 {
+		t.Errorf("expired page was not returned to the free list: frees=%d", stats.Frees)
+	}
+}
+
+func TestPageCacheReadAhead(t *testing.T) {
+	const size = 64 * 1024
+	data := make([]byte, size)
+	rand.New(rand.NewSource(7)).Read(data)
+
+	cache := pagecache.New(
+		pagecache.PageSize(1024),
+		pagecache.PageCount(1024),
+		pagecache.ReadAhead(4),
+	)
+
+	file := cache.NewFile(1, bytes.NewReader(data), size)
+	window := file.(interface{ ReadAheadWindow() int64 })
+	b := make([]byte, 1024)
+
+	for i, want := range []int64{1, 2, 4, 4} {
+		off := int64(i) * 1024
+		if _, err := file.ReadAt(b, off); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b, data[off:off+1024]) {
+			t.Fatalf("wrong data read at offset %d", off)
+		}
+		if got := window.ReadAheadWindow(); got != want {
+			t.Errorf("wrong read-ahead window after sequential read %d: got=%d want=%d", i, got, want)
+		}
+	}
+
+	// Pages 4 to 7 were prefetched by the previous reads.
+	stats := cache.Stats()
+	if _, err := file.ReadAt(b, 4*1024); err != nil {
+		t.Fatal(err)
+	}
+	if hits := cache.Stats().Hits - stats.Hits; hits != 1 {
+		t.Errorf("reading a prefetched page was not a cache hit: hits=%d", hits)
+	}
+
+	if _, err := file.ReadAt(b, 32*1024); err != nil {
+		t.Fatal(err)
+	}
+	if got := window.ReadAheadWindow(); got != 0 {
+		t.Errorf("random read did not reset the read-ahead window: got=%d", got)
+	}
+}
+
+func TestPageCacheHashSeed(t *testing.T) {
+	const size = 64 * 1024
+	data := make([]byte, size)
+	seed := maphash.MakeSeed()
+
+	// Pages are reported bucket by bucket, each bucket holding two pages, so
+	// the layout of buckets can be reconstructed by grouping pages in pairs.
+	layout := func() string {
+		cache := pagecache.New(
+			pagecache.PageSize(4096),
+			pagecache.PageCount(1024),
+			pagecache.HashSeed(seed),
+		)
+		file := cache.NewFile(1, bytes.NewReader(data), size)
+		if _, err := file.ReadAt(make([]byte, size), 0); err != nil {
+			t.Fatal(err)
+		}
+
+		pages := []int64{}
+		cache.ForEachPage(func(id uint32, offset int64, resident bool) bool {
+			pages = append(pages, offset)
+			return true
+		})
+
+		buckets := make([]string, 0, len(pages)/2)
+		for i := 0; i < len(pages); i += 2 {
+			a, b := pages[i], pages[i+1]
+			if a > b {
+				a, b = b, a
+			}
+			buckets = append(buckets, fmt.Sprint(a, b))
+		}
+		return strings.Join(buckets, ",")
+	}
+
+	if l1, l2 := layout(), layout(); l1 != l2 {
+		t.Errorf("caches created with the same seed have different layouts:\n%s\n%s", l1, l2)
+	}
+}
+
+func TestPageCacheRecentEvictions(t *testing.T) {
+	const pageCount = 512
+	const size = 4 * 4096 * pageCount
+	data := make([]byte, size)
+
+	cache := pagecache.New(
+		pagecache.PageSize(4096),
+		pagecache.PageCount(pageCount),
+		pagecache.EvictionLog(4),
+	)
+
+	// The files hold eight times more pages than the cache, so reading them
+	// must evict pages regardless of how the pages are spread across buckets.
+	f1 := cache.NewFile(1, bytes.NewReader(data), size)
+	f2 := cache.NewFile(2, bytes.NewReader(data), size)
+	b := make([]byte, size)
+	for i := 0; i < 10; i++ {
+		if _, err := f1.ReadAt(b, 0); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f2.ReadAt(b, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	evictions := cache.RecentEvictions()
+	if len(evictions) != 4 {
+		t.Fatalf("wrong number of recent evictions: got=%d want=4", len(evictions))
+	}
+	for _, e := range evictions {
+		if (e.ID != 1 && e.ID != 2) || e.Offset < 0 || e.Offset >= size || e.Offset%4096 != 0 {
+			t.Errorf("invalid eviction: %+v", e)
+		}
+	}
+	if n := cache.Stats().Evictions; n < 4 {
+		t.Errorf("wrong number of evictions: got=%d want>=4", n)
+	}
+
+	if evictions := pagecache.New().RecentEvictions(); evictions != nil {
+		t.Errorf("eviction log reported evictions while disabled: %v", evictions)
+	}
+}
+
+func TestPageCachePrefault(t *testing.T) {
+	cache := pagecache.New(
+		pagecache.PageSize(1024),
+		pagecache.PageCount(1024),
+		pagecache.Prefault(true),
+	)
+
+	data := make([]byte, 8192)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	file := cache.NewFile(1, bytes.NewReader(data), int64(len(data)))
+
+	buf := make([]byte, len(data))
+	if _, err := file.ReadAt(buf, 0); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf, data) {
+		t.Error("wrong data read from prefaulted cache")
+	}
+}
+
+func TestPageCacheMemoryUsage(t *testing.T) {
+	cache := pagecache.New(
+		pagecache.PageSize(1024),
+		pagecache.PageCount(1024),
+	)
+
+	size := cache.MemoryUsage()
+	if size < 1024*1024 {
+		t.Errorf("memory usage does not account for page memory: %d", size)
+	}
+
+	data := make([]byte, 8192)
+	if _, err := cache.NewFile(1, bytes.NewReader(data), 8192).ReadAt(data, 0); err != nil {
+		t.Fatal(err)
+	}
+	if cache.MemoryUsage() <= size {
+		t.Error("memory usage did not grow after caching pages")
+	}
+}
+
+func TestPageCacheCompact(t *testing.T) {
+	const pageSize = 1024
+	const pageCount = 1024
+	cache := pagecache.New(
+		pagecache.PageSize(pageSize),
+		pagecache.PageCount(pageCount),
+	)
+
+	// Fill all the pages of the cache so the free lists are empty.
+	data := make([]byte, 2*pageSize*pageCount)
+	file := cache.NewFile(1, bytes.NewReader(data), int64(len(data)))
+	for off := 0; off < len(data); off += pageSize {
+		if _, err := file.ReadAt(data[off:off+pageSize], int64(off)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	size := cache.MemoryUsage()
+	cache.Compact()
+	if compacted := cache.MemoryUsage(); compacted >= size {
+		t.Errorf("memory usage did not decrease after compaction: got=%d want<%d", compacted, size)
+	}
+
+	// The cache must remain usable after compaction.
+	buf := make([]byte, pageSize)
+	for off := 0; off < len(data); off += pageSize {
+		if _, err := file.ReadAt(buf, int64(off)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if stats := cache.Stats(); stats.Evictions == 0 {
+		t.Error("no evictions recorded after reading past the cache capacity")
+	}
+}
+
 func BenchmarkPageCacheNoEvictions(b *testing.B) {
 	// 4 MiB cache, no evictions
 	benchmarkPageCache(b,